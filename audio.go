@@ -8,6 +8,7 @@ package reisen
 import "C"
 import (
 	"fmt"
+	"io"
 	"unsafe"
 )
 
@@ -22,9 +23,10 @@ const (
 // audio frames consisting of audio samples.
 type AudioStream struct {
 	baseStream
-	swrCtx     *C.SwrContext
-	buffer     *C.uint8_t
-	bufferSize C.int
+	swrCtx      *C.SwrContext
+	buffer      *C.uint8_t
+	bufferSize  C.int
+	samplesSize int
 }
 
 // ChannelCount returns the number of channels
@@ -54,6 +56,27 @@ func (audio *AudioStream) Open() error {
 		return err
 	}
 
+	audio.buffer = nil
+
+	// The sample rate may be unknown
+	// until the first frame is decoded.
+	if audio.codecCtx.sample_rate <= 0 {
+		return nil
+	}
+
+	return audio.initResampler()
+}
+
+// initResampler creates the SWR context
+// for converting the decoded samples.
+func (audio *AudioStream) initResampler() error {
+	// Some decoders don't report the channel
+	// layout, only the number of channels.
+	if audio.codecCtx.channel_layout == 0 {
+		audio.codecCtx.channel_layout = C.uint64_t(
+			C.av_get_default_channel_layout(audio.codecCtx.channels))
+	}
+
 	audio.swrCtx = C.swr_alloc_set_opts(nil,
 		C.AV_CH_FRONT_LEFT|C.AV_CH_FRONT_RIGHT,
 		C.AV_SAMPLE_FMT_DBL, audio.codecCtx.sample_rate,
@@ -69,35 +92,133 @@ func (audio *AudioStream) Open() error {
 	status := C.swr_init(audio.swrCtx)
 
 	if status < 0 {
-		return fmt.Errorf(
-			"%d: couldn't initialize the SWR context", status)
+		return newAVError(status, "swr_init")
 	}
 
-	audio.buffer = nil
-
 	return nil
 }
 
 // ReadFrame reads a new frame from the stream.
 func (audio *AudioStream) ReadFrame() (Frame, bool, error) {
-	return audio.ReadAudioFrame()
+	frame, ok, err := audio.ReadAudioFrame()
+
+	// The nil frame pointer shouldn't
+	// become a non-nil interface.
+	if frame == nil {
+		return nil, ok, err
+	}
+
+	return frame, ok, err
 }
 
 // ReadAudioFrame reads a new audio frame from the stream.
+//
+// If the packet is depleted and the decoder
+// needs more data, no frame is returned with
+// 'true'. If the stream is completely drained,
+// 'false' is returned.
 func (audio *AudioStream) ReadAudioFrame() (*AudioFrame, bool, error) {
-	ok, err := audio.read()
+	size, ok, err := audio.samples()
 
 	if err != nil {
 		return nil, false, err
 	}
 
+	if size < 0 {
+		return nil, ok, nil
+	}
+
+	frame := audio.newFrame()
+	data := frame.buffer(size)
+	copy(data, unsafe.Slice((*byte)(unsafe.Pointer(
+		audio.buffer)), size))
+
+	frame.stream = audio
+	frame.pts = int64(audio.frame.pts)
+	frame.data = data
+	frame.indexCoded = int(audio.frame.coded_picture_number)
+	frame.indexDisplay = int(audio.frame.display_picture_number)
+
+	return frame, true, nil
+}
+
+// ReadAudioFrameInto reads the samples of the next
+// audio frame from the stream into the slice without
+// allocating memory. The samples of the channels
+// are interleaved.
+//
+// The number of the values written is returned,
+// 0 if no frame was obtained. The second returned
+// value has the same meaning as in ReadAudioFrame.
+//
+// If the slice is too short, the number of the
+// values needed is returned with an error
+// wrapping io.ErrShortBuffer. The samples are
+// kept until they're read into a larger slice.
+func (audio *AudioStream) ReadAudioFrameInto(dst []float64) (int, bool, error) {
+	size, ok, err := audio.samples()
+
+	if err != nil || size < 0 {
+		return 0, ok, err
+	}
+
+	count := size / int(unsafe.Sizeof(float64(0)))
+
+	if count > len(dst) {
+		audio.held = true
+
+		return count, true, fmt.Errorf(
+			"couldn't read %d samples into the slice of length %d: %w",
+			count, len(dst), io.ErrShortBuffer)
+	}
+
+	copy(dst[:count], unsafe.Slice((*float64)(unsafe.Pointer(
+		audio.buffer)), count))
+
+	return count, true, nil
+}
+
+// samples returns the size of the samples kept
+// after the previous read in bytes or decodes
+// the next frame if there are none.
+func (audio *AudioStream) samples() (int, bool, error) {
+	if audio.held {
+		audio.held = false
+
+		return audio.samplesSize, true, nil
+	}
+
+	return audio.decode()
+}
+
+// decode decodes the next frame and converts
+// its samples into the internal buffer.
+//
+// The size of the samples in bytes is returned,
+// -1 if no frame was obtained. The second value
+// is 'false' if the stream is drained.
+func (audio *AudioStream) decode() (int, bool, error) {
+	ok, err := audio.read()
+
+	if err != nil {
+		return -1, false, err
+	}
+
 	if ok && audio.skip {
-		return nil, true, nil
+		return -1, true, nil
 	}
 
 	// No more data.
 	if !ok {
-		return nil, false, nil
+		return -1, false, nil
+	}
+
+	if audio.swrCtx == nil {
+		err = audio.initResampler()
+
+		if err != nil {
+			return -1, false, err
+		}
 	}
 
 	maxBufferSize := C.av_samples_get_buffer_size(
@@ -106,8 +227,7 @@ func (audio *AudioStream) ReadAudioFrame() (*AudioFrame, bool, error) {
 		C.AV_SAMPLE_FMT_DBL, 1)
 
 	if maxBufferSize < 0 {
-		return nil, false, fmt.Errorf(
-			"%d: couldn't get the max buffer size", maxBufferSize)
+		return -1, false, newAVError(maxBufferSize, "av_samples_get_buffer_size")
 	}
 
 	if maxBufferSize > audio.bufferSize {
@@ -121,7 +241,7 @@ func (audio *AudioStream) ReadAudioFrame() (*AudioFrame, bool, error) {
 		audio.bufferSize = maxBufferSize
 
 		if audio.buffer == nil {
-			return nil, false, fmt.Errorf(
+			return -1, false, fmt.Errorf(
 				"couldn't allocate an AV buffer")
 		}
 	}
@@ -131,18 +251,27 @@ func (audio *AudioStream) ReadAudioFrame() (*AudioFrame, bool, error) {
 		&audio.frame.data[0], audio.frame.nb_samples)
 
 	if gotSamples < 0 {
-		return nil, false, fmt.Errorf(
-			"%d: couldn't convert the audio frame", gotSamples)
+		return -1, false, newAVError(gotSamples, "swr_convert")
 	}
 
-	data := C.GoBytes(unsafe.Pointer(
-		audio.buffer), maxBufferSize)
-	frame := newAudioFrame(audio,
-		int64(audio.frame.pts),
-		int(audio.frame.coded_picture_number),
-		int(audio.frame.display_picture_number), data)
+	audio.samplesSize = int(maxBufferSize)
 
-	return frame, true, nil
+	return audio.samplesSize, true, nil
+}
+
+// newFrame returns an audio frame from the
+// frame pool of the stream if it's used.
+func (audio *AudioStream) newFrame() *AudioFrame {
+	if audio.pool != nil {
+		if frame, ok := audio.pool.Get().(*AudioFrame); ok {
+			return frame
+		}
+	}
+
+	frame := new(AudioFrame)
+	frame.pool = audio.pool
+
+	return frame
 }
 
 // Close closes the audio stream and
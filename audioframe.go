@@ -22,15 +22,26 @@ func (frame *AudioFrame) Data() []byte {
 	return frame.data
 }
 
-// newAudioFrame returns a newly created audio frame.
-func newAudioFrame(stream Stream, pts int64, indCoded, indDisplay int, data []byte) *AudioFrame {
-	frame := new(AudioFrame)
+// Release returns the frame to the frame
+// pool of its stream, so its memory is
+// reused for the next frames. The frame
+// must not be used after that.
+//
+// If the stream doesn't use the
+// frame pool, nothing is done.
+func (frame *AudioFrame) Release() {
+	if frame.pool != nil {
+		frame.pool.Put(frame)
+	}
+}
 
-	frame.stream = stream
-	frame.pts = pts
-	frame.data = data
-	frame.indexCoded = indCoded
-	frame.indexDisplay = indDisplay
+// buffer returns the data buffer of the frame
+// of the specified size reusing the memory
+// of the previous data if possible.
+func (frame *AudioFrame) buffer(size int) []byte {
+	if cap(frame.data) < size {
+		frame.data = make([]byte, size)
+	}
 
-	return frame
+	return frame.data[:size]
 }
@@ -0,0 +1,113 @@
+package reisen
+
+// #cgo pkg-config: libavformat libavutil
+// #include <libavformat/avformat.h>
+// #include <libavutil/mathematics.h>
+import "C"
+import (
+	"fmt"
+	"time"
+	"unsafe"
+)
+
+// Chapter is a chapter marker
+// of the media container.
+type Chapter struct {
+	// ID is the unique identifier
+	// of the chapter.
+	ID int64
+	// Start is the time offset since
+	// the start of the media at which
+	// the chapter begins.
+	Start time.Duration
+	// End is the time offset since
+	// the start of the media at which
+	// the chapter ends.
+	End time.Duration
+	// Title is the title of the chapter
+	// or "" if it's not specified.
+	Title string
+	// Metadata holds all the
+	// metadata tags of the chapter.
+	Metadata map[string]string
+}
+
+// Chapters returns the chapter
+// markers of the media.
+func (media *Media) Chapters() []Chapter {
+	chapters := make([]Chapter, 0, media.ctx.nb_chapters)
+
+	// The chapter timestamps are absolute, so
+	// the start time of the media (non-zero e.g.
+	// for MPEG-TS) is subtracted from them.
+	var start time.Duration
+
+	if media.ctx.start_time != C.AV_NOPTS_VALUE {
+		start = time.Duration(media.ctx.start_time) * time.Microsecond
+	}
+
+	for _, chapter := range media.chapters() {
+		chapters = append(chapters, Chapter{
+			ID:       int64(chapter.id),
+			Start:    chapterTime(chapter, chapter.start) - start,
+			End:      chapterTime(chapter, chapter.end) - start,
+			Title:    dictionaryValue(chapter.metadata, "title"),
+			Metadata: dictionaryMap(chapter.metadata),
+		})
+	}
+
+	return chapters
+}
+
+// SeekChapter rewinds the whole media
+// to the start of the chapter with the
+// specified index.
+//
+// The position is determined by the first
+// video stream or by the first stream if
+// there are no video streams in the media.
+func (media *Media) SeekChapter(index int) error {
+	chapters := media.chapters()
+
+	if index < 0 || index >= len(chapters) {
+		return fmt.Errorf(
+			"couldn't seek to the chapter %d: the media has %d chapters",
+			index, len(chapters))
+	}
+
+	if len(media.streams) <= 0 {
+		return fmt.Errorf(
+			"couldn't seek to the chapter %d: the media has no streams",
+			index)
+	}
+
+	stream := media.streams[0]
+
+	if videoStreams := media.VideoStreams(); len(videoStreams) > 0 {
+		stream = videoStreams[0]
+	}
+
+	return stream.Rewind(chapterTime(
+		chapters[index], chapters[index].start))
+}
+
+// chapters returns the libAV
+// chapters of the media.
+func (media *Media) chapters() []*C.AVChapter {
+	if media.ctx.chapters == nil || media.ctx.nb_chapters <= 0 {
+		return nil
+	}
+
+	return unsafe.Slice(media.ctx.chapters,
+		media.ctx.nb_chapters)
+}
+
+// chapterTime converts the value in the
+// time base units of the chapter into
+// the time duration.
+func chapterTime(chapter *C.AVChapter, value C.int64_t) time.Duration {
+	us := C.av_rescale_q(value, chapter.time_base,
+		C.AVRational{num: 1, den: C.AV_TIME_BASE})
+
+	return time.Duration(us) * time.Microsecond
+}
@@ -0,0 +1,245 @@
+package reisen
+
+// #cgo pkg-config: libavcodec libavutil
+// #include <libavcodec/avcodec.h>
+// #include <libavutil/mem.h>
+// #include <stdlib.h>
+import "C"
+import (
+	"fmt"
+	"unsafe"
+)
+
+// Decoder decodes packets which are not
+// necessarily read from the media, e.g.
+// stored, reordered or obtained from
+// a custom source.
+type Decoder struct {
+	stream Stream
+	packet *C.AVPacket
+	params *C.AVCodecParameters
+}
+
+// Decode decodes the packet and returns
+// all the frames obtained from it.
+//
+// If the packet is nil, the decoder is
+// drained, and all the frames buffered
+// inside it are returned. After that
+// the decoder accepts new packets again.
+func (dec *Decoder) Decode(pkt *Packet) ([]Frame, error) {
+	if pkt == nil {
+		err := dec.stream.Flush()
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer dec.stream.restart()
+	} else {
+		err := pkt.fill(dec.packet)
+
+		if err != nil {
+			return nil, err
+		}
+
+		err = dec.stream.feed(dec.packet)
+		C.av_packet_unref(dec.packet)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	frames := []Frame{}
+
+	for {
+		frame, ok, err := dec.stream.ReadFrame()
+
+		if err != nil {
+			return frames, err
+		}
+
+		if !ok || frame == nil {
+			break
+		}
+
+		frames = append(frames, frame)
+	}
+
+	return frames, nil
+}
+
+// Close closes the decoder
+// and frees its memory.
+func (dec *Decoder) Close() error {
+	err := dec.stream.Close()
+
+	if err != nil {
+		return err
+	}
+
+	C.av_packet_free(&dec.packet)
+
+	if dec.params != nil {
+		C.avcodec_parameters_free(&dec.params)
+	}
+
+	return nil
+}
+
+// NewDecoder creates a new decoder
+// for the packets of the stream.
+//
+// The decoder is independent of the
+// stream, so the stream doesn't need
+// to be opened for decoding. The decoder
+// keeps a copy of the stream properties,
+// so it can be used after the media
+// is closed.
+func NewDecoder(stream Stream) (*Decoder, error) {
+	params := C.avcodec_parameters_alloc()
+
+	if params == nil {
+		return nil, fmt.Errorf(
+			"couldn't allocate codec parameters")
+	}
+
+	var (
+		decStream Stream
+		err       error
+	)
+
+	switch s := stream.(type) {
+	case *VideoStream:
+		video := new(VideoStream)
+		video.baseStream, err = s.detached(params)
+		decStream = video
+
+	case *AudioStream:
+		audio := new(AudioStream)
+		audio.baseStream, err = s.detached(params)
+		decStream = audio
+
+	default:
+		err = fmt.Errorf(
+			"couldn't create a decoder for the stream %d: %w",
+			stream.Index(), ErrDecoderNotFound)
+	}
+
+	if err != nil {
+		C.avcodec_parameters_free(&params)
+		return nil, err
+	}
+
+	return newDecoder(decStream, params)
+}
+
+// NewDecoderFromCodec creates a new decoder
+// by the codec name (e.g. "h264", "hevc" or
+// "aac") and the codec extradata which can
+// be nil if the codec doesn't need it.
+//
+// The packet timestamps are considered
+// to be in microseconds (1/TimeBase).
+func NewDecoderFromCodec(codecName string, extradata []byte) (*Decoder, error) {
+	name := C.CString(codecName)
+	codec := C.avcodec_find_decoder_by_name(name)
+	C.free(unsafe.Pointer(name))
+
+	if codec == nil {
+		return nil, fmt.Errorf(
+			"couldn't find the decoder %s: %w",
+			codecName, ErrDecoderNotFound)
+	}
+
+	params := C.avcodec_parameters_alloc()
+
+	if params == nil {
+		return nil, fmt.Errorf(
+			"couldn't allocate codec parameters")
+	}
+
+	params.codec_type = codec._type
+	params.codec_id = codec.id
+
+	if len(extradata) > 0 {
+		params.extradata = (*C.uint8_t)(C.av_mallocz(C.size_t(
+			len(extradata) + C.AV_INPUT_BUFFER_PADDING_SIZE)))
+
+		if params.extradata == nil {
+			C.avcodec_parameters_free(&params)
+
+			return nil, fmt.Errorf(
+				"couldn't allocate the codec extradata")
+		}
+
+		copy(unsafe.Slice((*byte)(unsafe.Pointer(
+			params.extradata)), len(extradata)), extradata)
+		params.extradata_size = C.int(len(extradata))
+	}
+
+	// The packet timestamps are
+	// in microseconds.
+	base := baseStream{
+		codecParams: params,
+		codec:       codec,
+		info: streamInfo{
+			index:     -1,
+			timeBase:  C.AVRational{num: 1, den: C.AV_TIME_BASE},
+			startTime: NoTimestamp,
+		},
+	}
+
+	var stream Stream
+
+	switch codec._type {
+	case C.AVMEDIA_TYPE_VIDEO:
+		stream = &VideoStream{baseStream: base}
+
+	case C.AVMEDIA_TYPE_AUDIO:
+		stream = &AudioStream{baseStream: base}
+
+	default:
+		C.avcodec_parameters_free(&params)
+
+		return nil, fmt.Errorf(
+			"the codec %s is neither video nor audio", codecName)
+	}
+
+	return newDecoder(stream, params)
+}
+
+// newDecoder opens the stream for decoding
+// and creates a new decoder on top of it.
+func newDecoder(stream Stream, params *C.AVCodecParameters) (*Decoder, error) {
+	dec := &Decoder{
+		stream: stream,
+		params: params,
+	}
+
+	dec.packet = C.av_packet_alloc()
+
+	if dec.packet == nil {
+		if params != nil {
+			C.avcodec_parameters_free(&dec.params)
+		}
+
+		return nil, fmt.Errorf(
+			"couldn't allocate a new packet")
+	}
+
+	err := stream.Open()
+
+	if err != nil {
+		C.av_packet_free(&dec.packet)
+
+		if params != nil {
+			C.avcodec_parameters_free(&dec.params)
+		}
+
+		return nil, err
+	}
+
+	return dec, nil
+}
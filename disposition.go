@@ -0,0 +1,113 @@
+package reisen
+
+// #cgo pkg-config: libavformat
+// #include <libavformat/avformat.h>
+import "C"
+import "strings"
+
+// Disposition is a set of flags
+// describing the purpose of the stream.
+type Disposition int
+
+const (
+	// DispositionDefault means the stream should
+	// be chosen by default among the streams of
+	// the same type.
+	DispositionDefault Disposition = C.AV_DISPOSITION_DEFAULT
+	// DispositionDub means the stream
+	// is a dubbed track.
+	DispositionDub Disposition = C.AV_DISPOSITION_DUB
+	// DispositionOriginal means the stream
+	// is in the original language.
+	DispositionOriginal Disposition = C.AV_DISPOSITION_ORIGINAL
+	// DispositionComment means the stream
+	// is a commentary track.
+	DispositionComment Disposition = C.AV_DISPOSITION_COMMENT
+	// DispositionLyrics means the
+	// stream contains song lyrics.
+	DispositionLyrics Disposition = C.AV_DISPOSITION_LYRICS
+	// DispositionKaraoke means the stream
+	// contains karaoke audio.
+	DispositionKaraoke Disposition = C.AV_DISPOSITION_KARAOKE
+	// DispositionForced means the stream should
+	// be displayed regardless of the user
+	// preferences (e.g. forced subtitles).
+	DispositionForced Disposition = C.AV_DISPOSITION_FORCED
+	// DispositionHearingImpaired means the stream
+	// is intended for hearing impaired audiences.
+	DispositionHearingImpaired Disposition = C.AV_DISPOSITION_HEARING_IMPAIRED
+	// DispositionVisualImpaired means the stream
+	// is intended for visually impaired audiences.
+	DispositionVisualImpaired Disposition = C.AV_DISPOSITION_VISUAL_IMPAIRED
+	// DispositionCleanEffects means the stream
+	// contains no voice, only the effects.
+	DispositionCleanEffects Disposition = C.AV_DISPOSITION_CLEAN_EFFECTS
+	// DispositionAttachedPicture means the
+	// stream is a single attached picture
+	// (e.g. the cover art of an album).
+	DispositionAttachedPicture Disposition = C.AV_DISPOSITION_ATTACHED_PIC
+	// DispositionTimedThumbnails means the
+	// stream contains thumbnails of the video.
+	DispositionTimedThumbnails Disposition = C.AV_DISPOSITION_TIMED_THUMBNAILS
+	// DispositionCaptions means the stream
+	// contains closed captions.
+	DispositionCaptions Disposition = C.AV_DISPOSITION_CAPTIONS
+	// DispositionDescriptions means the stream
+	// contains textual descriptions of the video.
+	DispositionDescriptions Disposition = C.AV_DISPOSITION_DESCRIPTIONS
+	// DispositionMetadata means the
+	// stream contains only metadata.
+	DispositionMetadata Disposition = C.AV_DISPOSITION_METADATA
+	// DispositionDependent means the stream is
+	// intended to be mixed with another stream.
+	DispositionDependent Disposition = C.AV_DISPOSITION_DEPENDENT
+	// DispositionStillImage means the stream
+	// is a sequence of still images.
+	DispositionStillImage Disposition = C.AV_DISPOSITION_STILL_IMAGE
+)
+
+// dispositionNames are the names of
+// the disposition flags in the order
+// of their values.
+var dispositionNames = []struct {
+	flag Disposition
+	name string
+}{
+	{DispositionDefault, "default"},
+	{DispositionDub, "dub"},
+	{DispositionOriginal, "original"},
+	{DispositionComment, "comment"},
+	{DispositionLyrics, "lyrics"},
+	{DispositionKaraoke, "karaoke"},
+	{DispositionForced, "forced"},
+	{DispositionHearingImpaired, "hearing_impaired"},
+	{DispositionVisualImpaired, "visual_impaired"},
+	{DispositionCleanEffects, "clean_effects"},
+	{DispositionAttachedPicture, "attached_pic"},
+	{DispositionTimedThumbnails, "timed_thumbnails"},
+	{DispositionCaptions, "captions"},
+	{DispositionDescriptions, "descriptions"},
+	{DispositionMetadata, "metadata"},
+	{DispositionDependent, "dependent"},
+	{DispositionStillImage, "still_image"},
+}
+
+// Has returns 'true' if all
+// the flags are set.
+func (disposition Disposition) Has(flags Disposition) bool {
+	return disposition&flags == flags
+}
+
+// String returns the names of the
+// flags set separated by '+'.
+func (disposition Disposition) String() string {
+	names := []string{}
+
+	for _, entry := range dispositionNames {
+		if disposition.Has(entry.flag) {
+			names = append(names, entry.name)
+		}
+	}
+
+	return strings.Join(names, "+")
+}
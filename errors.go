@@ -1,5 +1,16 @@
 package reisen
 
+// #cgo pkg-config: libavutil
+// #include <libavutil/avutil.h>
+// #include <libavutil/error.h>
+// #include <errno.h>
+import "C"
+import (
+	"fmt"
+	"io"
+)
+
+// ErrorType is a raw libAV error code.
 type ErrorType int
 
 const (
@@ -15,3 +26,101 @@ const (
 	// reaching the end of the media file.
 	ErrorEndOfFile ErrorType = -541478725
 )
+
+var (
+	// ErrAgain means the output is not available
+	// in the current state and more input
+	// should be provided.
+	ErrAgain = newAVError(-C.EAGAIN, "")
+	// ErrEOF means the end of the
+	// media file has been reached.
+	//
+	// It also matches io.EOF.
+	ErrEOF = newAVError(C.AVERROR_EOF, "")
+	// ErrInvalidData means the data
+	// is invalid or corrupted.
+	ErrInvalidData = newAVError(C.AVERROR_INVALIDDATA, "")
+	// ErrInvalidValue means the function
+	// call argument is invalid.
+	ErrInvalidValue = newAVError(-C.EINVAL, "")
+	// ErrNoMemory means libAV
+	// couldn't allocate memory.
+	ErrNoMemory = newAVError(-C.ENOMEM, "")
+	// ErrIO means an input or
+	// output operation failed.
+	ErrIO = newAVError(-C.EIO, "")
+	// ErrExit means the blocking
+	// operation was interrupted.
+	ErrExit = newAVError(C.AVERROR_EXIT, "")
+	// ErrDecoderNotFound means there's no
+	// decoder available for the codec.
+	ErrDecoderNotFound = newAVError(C.AVERROR_DECODER_NOT_FOUND, "")
+	// ErrDemuxerNotFound means there's no
+	// demuxer available for the format.
+	ErrDemuxerNotFound = newAVError(C.AVERROR_DEMUXER_NOT_FOUND, "")
+	// ErrStreamNotFound means the media
+	// has no stream matching the request.
+	ErrStreamNotFound = newAVError(C.AVERROR_STREAM_NOT_FOUND, "")
+	// ErrBSFNotFound means there's no
+	// bitstream filter with such a name.
+	ErrBSFNotFound = newAVError(C.AVERROR_BSF_NOT_FOUND, "")
+	// ErrOptionNotFound means there's
+	// no option with such a name.
+	ErrOptionNotFound = newAVError(C.AVERROR_OPTION_NOT_FOUND, "")
+	// ErrPatchWelcome means the feature
+	// is not implemented in libAV yet.
+	ErrPatchWelcome = newAVError(C.AVERROR_PATCHWELCOME, "")
+	// ErrUnknown means libAV failed
+	// for an unknown reason.
+	ErrUnknown = newAVError(C.AVERROR_UNKNOWN, "")
+)
+
+// AVError is an error
+// returned by libAV.
+type AVError struct {
+	// Code is the libAV error code.
+	Code int
+	// Message is the description
+	// of the error code.
+	Message string
+	// Op is the name of the libAV
+	// function which failed.
+	Op string
+}
+
+// Error returns the error message
+// prefixed by the operation name.
+func (err *AVError) Error() string {
+	if err.Op == "" {
+		return err.Message
+	}
+
+	return fmt.Sprintf("%s: %s", err.Op, err.Message)
+}
+
+// Is reports whether the error has
+// the same code as the target. The
+// end of file error also matches io.EOF.
+func (err *AVError) Is(target error) bool {
+	if target == io.EOF {
+		return err.Code == int(C.AVERROR_EOF)
+	}
+
+	avErr, ok := target.(*AVError)
+
+	return ok && avErr.Code == err.Code
+}
+
+// newAVError creates a new error out
+// of the libAV error code returned
+// by the operation.
+func newAVError(code C.int, op string) *AVError {
+	buf := make([]C.char, C.AV_ERROR_MAX_STRING_SIZE)
+	C.av_strerror(code, &buf[0], C.size_t(len(buf)))
+
+	return &AVError{
+		Code:    int(code),
+		Message: C.GoString(&buf[0]),
+		Op:      op,
+	}
+}
@@ -20,6 +20,7 @@ func main() {
 	fmt.Println("Format long name:", media.FormatLongName())
 	fmt.Println("MIME type:", media.FormatMIMEType())
 	fmt.Println("Number of streams:", media.StreamCount())
+	fmt.Println("Metadata:", media.Metadata())
 	fmt.Println()
 
 	// Enumerate the media file streams.
@@ -40,6 +41,7 @@ func main() {
 		fmt.Printf("Time base: %d/%d\n", tbNum, tbDen)
 		fmt.Printf("Frame rate: %d/%d\n", fpsNum, fpsDen)
 		fmt.Println("Frame count:", stream.FrameCount())
+		fmt.Println("Language:", stream.Language())
 		fmt.Println()
 	}
 
@@ -73,30 +75,29 @@ func main() {
 				handleError(err)
 			}
 
-			videoFrame, gotFrame, err := s.ReadVideoFrame()
-			handleError(err)
-
-			// If the media file is
-			// depleted.
-			if !gotFrame {
-				break
-			}
+			// A packet can contain several
+			// frames, so read all of them.
+			for {
+				videoFrame, gotFrame, err := s.ReadVideoFrame()
+				handleError(err)
 
-			// If the packet doesn't
-			// contain a whole frame,
-			// just skip it.
-			if videoFrame == nil {
-				continue
-			}
+				// If the media file is depleted
+				// or the packet doesn't contain
+				// any more frames, proceed to
+				// the next packet.
+				if !gotFrame || videoFrame == nil {
+					break
+				}
 
-			pts, err := videoFrame.PresentationOffset()
-			handleError(err)
+				pts, err := videoFrame.PresentationOffset()
+				handleError(err)
 
-			fmt.Println("Presentation duration offset:", pts)
-			fmt.Println("Number of pixels:", len(videoFrame.Image().Pix))
-			fmt.Println("Coded picture number:", videoFrame.IndexCoded())
-			fmt.Println("Display picture number:", videoFrame.IndexDisplay())
-			fmt.Println()
+				fmt.Println("Presentation duration offset:", pts)
+				fmt.Println("Number of pixels:", len(videoFrame.Data()))
+				fmt.Println("Coded picture number:", videoFrame.IndexCoded())
+				fmt.Println("Display picture number:", videoFrame.IndexDisplay())
+				fmt.Println()
+			}
 
 		case reisen.StreamAudio:
 			s := media.Streams()[pkt.StreamIndex()].(*reisen.AudioStream)
@@ -106,25 +107,23 @@ func main() {
 				handleError(err)
 			}
 
-			audioFrame, gotFrame, err := s.ReadAudioFrame()
-			handleError(err)
-
-			if !gotFrame {
-				break
-			}
+			for {
+				audioFrame, gotFrame, err := s.ReadAudioFrame()
+				handleError(err)
 
-			if audioFrame == nil {
-				continue
-			}
+				if !gotFrame || audioFrame == nil {
+					break
+				}
 
-			pts, err := audioFrame.PresentationOffset()
-			handleError(err)
+				pts, err := audioFrame.PresentationOffset()
+				handleError(err)
 
-			fmt.Println("Presentation duration offset:", pts)
-			fmt.Println("Data length:", len(audioFrame.Data()))
-			fmt.Println("Coded picture number:", audioFrame.IndexCoded())
-			fmt.Println("Display picture number:", audioFrame.IndexDisplay())
-			fmt.Println()
+				fmt.Println("Presentation duration offset:", pts)
+				fmt.Println("Data length:", len(audioFrame.Data()))
+				fmt.Println("Coded picture number:", audioFrame.IndexCoded())
+				fmt.Println("Display picture number:", audioFrame.IndexDisplay())
+				fmt.Println()
+			}
 		}
 	}
 
@@ -40,14 +40,24 @@ func readVideoAndAudio(media *reisen.Media) (<-chan *image.RGBA, <-chan [2]float
 		return nil, nil, nil, err
 	}
 
-	videoStream := media.VideoStreams()[0]
+	videoStream, err := media.BestVideoStream()
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	err = videoStream.Open()
 
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	audioStream := media.AudioStreams()[0]
+	audioStream, err := media.BestAudioStream()
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	err = audioStream.Open()
 
 	if err != nil {
@@ -86,52 +96,32 @@ func readVideoAndAudio(media *reisen.Media) (<-chan *image.RGBA, <-chan [2]float
 			switch packet.Type() {
 			case reisen.StreamVideo:
 				s := media.Streams()[packet.StreamIndex()].(*reisen.VideoStream)
-				videoFrame, gotFrame, err := s.ReadVideoFrame()
 
-				if err != nil {
-					go func(err error) {
-						errs <- err
-					}(err)
-				}
+				// A packet can contain several
+				// frames, so read all of them.
+				for {
+					videoFrame, gotFrame, err := s.ReadVideoFrame()
 
-				if !gotFrame {
-					break
-				}
+					if err != nil {
+						go func(err error) {
+							errs <- err
+						}(err)
+					}
 
-				if videoFrame == nil {
-					continue
-				}
+					if !gotFrame || videoFrame == nil {
+						break
+					}
 
-				frameBuffer <- videoFrame.Image()
+					frameBuffer <- videoFrame.Image().(*image.RGBA)
+				}
 
 			case reisen.StreamAudio:
 				s := media.Streams()[packet.StreamIndex()].(*reisen.AudioStream)
-				audioFrame, gotFrame, err := s.ReadAudioFrame()
-
-				if err != nil {
-					go func(err error) {
-						errs <- err
-					}(err)
-				}
-
-				if !gotFrame {
-					break
-				}
 
-				if audioFrame == nil {
-					continue
-				}
-
-				// Turn the raw byte data into
-				// audio samples of type [2]float64.
-				reader := bytes.NewReader(audioFrame.Data())
-
-				// See the README.md file for
-				// detailed scheme of the sample structure.
-				for reader.Len() > 0 {
-					sample := [2]float64{0, 0}
-					var result float64
-					err = binary.Read(reader, binary.LittleEndian, &result)
+				// A packet can contain several
+				// frames, so read all of them.
+				for {
+					audioFrame, gotFrame, err := s.ReadAudioFrame()
 
 					if err != nil {
 						go func(err error) {
@@ -139,18 +129,40 @@ func readVideoAndAudio(media *reisen.Media) (<-chan *image.RGBA, <-chan [2]float
 						}(err)
 					}
 
-					sample[0] = result
+					if !gotFrame || audioFrame == nil {
+						break
+					}
 
-					err = binary.Read(reader, binary.LittleEndian, &result)
+					// Turn the raw byte data into
+					// audio samples of type [2]float64.
+					reader := bytes.NewReader(audioFrame.Data())
 
-					if err != nil {
-						go func(err error) {
-							errs <- err
-						}(err)
-					}
+					// See the README.md file for
+					// detailed scheme of the sample structure.
+					for reader.Len() > 0 {
+						sample := [2]float64{0, 0}
+						var result float64
+						err = binary.Read(reader, binary.LittleEndian, &result)
+
+						if err != nil {
+							go func(err error) {
+								errs <- err
+							}(err)
+						}
 
-					sample[1] = result
-					sampleBuffer <- sample
+						sample[0] = result
+
+						err = binary.Read(reader, binary.LittleEndian, &result)
+
+						if err != nil {
+							go func(err error) {
+								errs <- err
+							}(err)
+						}
+
+						sample[1] = result
+						sampleBuffer <- sample
+					}
 				}
 			}
 		}
@@ -40,14 +40,24 @@ func readVideoAndAudio(media *reisen.Media) (<-chan *image.RGBA, <-chan [2]float
 		return nil, nil, nil, err
 	}
 
-	videoStream := media.VideoStreams()[0]
+	videoStream, err := media.BestVideoStream()
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	err = videoStream.Open()
 
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	audioStream := media.AudioStreams()[0]
+	audioStream, err := media.BestAudioStream()
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	err = audioStream.Open()
 
 	if err != nil {
@@ -86,58 +96,35 @@ func readVideoAndAudio(media *reisen.Media) (<-chan *image.RGBA, <-chan [2]float
 			switch packet.Type() {
 			case reisen.StreamVideo:
 				s := media.Streams()[packet.StreamIndex()].(*reisen.VideoStream)
-				videoFrame, gotFrame, err := s.ReadVideoFrame()
 
-				if err != nil {
-					go func(err error) {
-						errs <- err
-					}(err)
-				}
+				// A packet can contain several
+				// frames, so read all of them.
+				for {
+					videoFrame, gotFrame, err := s.ReadVideoFrame()
 
-				if !gotFrame {
-					break
-				}
+					if err != nil {
+						go func(err error) {
+							errs <- err
+						}(err)
+					}
 
-				if videoFrame == nil {
-					continue
-				}
+					if !gotFrame || videoFrame == nil {
+						break
+					}
 
-				offset, err := videoFrame.PresentationOffset()
-				fmt.Println("video frame offset:", offset, err)
+					offset, err := videoFrame.PresentationOffset()
+					fmt.Println("video frame offset:", offset, err)
 
-				frameBuffer <- videoFrame.Image()
+					frameBuffer <- videoFrame.Image().(*image.RGBA)
+				}
 
 			case reisen.StreamAudio:
 				s := media.Streams()[packet.StreamIndex()].(*reisen.AudioStream)
-				audioFrame, gotFrame, err := s.ReadAudioFrame()
 
-				if err != nil {
-					go func(err error) {
-						errs <- err
-					}(err)
-				}
-
-				if !gotFrame {
-					break
-				}
-
-				if audioFrame == nil {
-					continue
-				}
-
-				offset, err := audioFrame.PresentationOffset()
-				fmt.Println("audio frame offset:", offset, err)
-
-				// Turn the raw byte data into
-				// audio samples of type [2]float64.
-				reader := bytes.NewReader(audioFrame.Data())
-
-				// See the README.md file for
-				// detailed scheme of the sample structure.
-				for reader.Len() > 0 {
-					sample := [2]float64{0, 0}
-					var result float64
-					err = binary.Read(reader, binary.LittleEndian, &result)
+				// A packet can contain several
+				// frames, so read all of them.
+				for {
+					audioFrame, gotFrame, err := s.ReadAudioFrame()
 
 					if err != nil {
 						go func(err error) {
@@ -145,18 +132,43 @@ func readVideoAndAudio(media *reisen.Media) (<-chan *image.RGBA, <-chan [2]float
 						}(err)
 					}
 
-					sample[0] = result
+					if !gotFrame || audioFrame == nil {
+						break
+					}
 
-					err = binary.Read(reader, binary.LittleEndian, &result)
+					offset, err := audioFrame.PresentationOffset()
+					fmt.Println("audio frame offset:", offset, err)
 
-					if err != nil {
-						go func(err error) {
-							errs <- err
-						}(err)
-					}
+					// Turn the raw byte data into
+					// audio samples of type [2]float64.
+					reader := bytes.NewReader(audioFrame.Data())
+
+					// See the README.md file for
+					// detailed scheme of the sample structure.
+					for reader.Len() > 0 {
+						sample := [2]float64{0, 0}
+						var result float64
+						err = binary.Read(reader, binary.LittleEndian, &result)
 
-					sample[1] = result
-					sampleBuffer <- sample
+						if err != nil {
+							go func(err error) {
+								errs <- err
+							}(err)
+						}
+
+						sample[0] = result
+
+						err = binary.Read(reader, binary.LittleEndian, &result)
+
+						if err != nil {
+							go func(err error) {
+								errs <- err
+							}(err)
+						}
+
+						sample[1] = result
+						sampleBuffer <- sample
+					}
 				}
 			}
 		}
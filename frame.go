@@ -10,6 +10,7 @@ package reisen
 import "C"
 import (
 	"fmt"
+	"sync"
 	"time"
 )
 
@@ -17,6 +18,7 @@ import (
 type Frame interface {
 	Data() []byte
 	PresentationOffset() (time.Duration, error)
+	Release()
 }
 
 // baseFrame contains the information
@@ -26,6 +28,7 @@ type baseFrame struct {
 	pts          int64
 	indexCoded   int
 	indexDisplay int
+	pool         *sync.Pool
 }
 
 // PresentationOffset returns the duration offset
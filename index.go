@@ -0,0 +1,493 @@
+package reisen
+
+// #cgo pkg-config: libavformat libavutil
+// #include <libavformat/avformat.h>
+// #include <libavutil/mathematics.h>
+import "C"
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// indexMagic is the signature of
+// the binary encoded index.
+var indexMagic = [4]byte{'R', 'S', 'N', 'I'}
+
+// indexVersion is the version of
+// the binary index encoding.
+const indexVersion uint8 = 2
+
+// IndexEntry describes a
+// keyframe packet of the stream.
+type IndexEntry struct {
+	// PTS is the presentation timestamp
+	// of the keyframe in the time base
+	// units of the stream.
+	PTS int64 `json:"pts"`
+	// DTS is the decoding timestamp of
+	// the keyframe in the time base units
+	// of the stream or NoTimestamp if
+	// it's unknown.
+	DTS int64 `json:"dts"`
+	// Position is the byte position of
+	// the keyframe packet in the media
+	// or -1 if it's unknown.
+	Position int64 `json:"pos"`
+	// Size is the size of the
+	// keyframe packet in bytes.
+	Size int `json:"size"`
+}
+
+// UnmarshalJSON decodes the entry treating
+// the missing DTS (e.g. in the indices
+// encoded by older versions) as unknown.
+func (entry *IndexEntry) UnmarshalJSON(data []byte) error {
+	type plainEntry IndexEntry
+
+	decoded := plainEntry{DTS: NoTimestamp}
+	err := json.Unmarshal(data, &decoded)
+
+	if err != nil {
+		return err
+	}
+
+	*entry = IndexEntry(decoded)
+
+	return nil
+}
+
+// StreamIndex holds the keyframes
+// of a single stream sorted by PTS.
+type StreamIndex struct {
+	// Stream is the index number
+	// of the stream in the media.
+	Stream int `json:"stream"`
+	// TimeBaseNum is the numerator
+	// of the stream time base.
+	TimeBaseNum int `json:"time_base_num"`
+	// TimeBaseDen is the denominator
+	// of the stream time base.
+	TimeBaseDen int `json:"time_base_den"`
+	// Entries are the keyframes
+	// of the stream.
+	Entries []IndexEntry `json:"entries"`
+}
+
+// Index is a keyframe index of the
+// media used for fast random access
+// without relying on the index of
+// the container.
+//
+// It can be encoded with encoding/json
+// or with MarshalBinary and reloaded
+// later for the same media.
+type Index struct {
+	Streams []StreamIndex `json:"streams"`
+}
+
+// Add records the packet in the
+// index if it's a keyframe.
+func (index *Index) Add(pkt *Packet) {
+	if !pkt.IsKeyframe() || pkt.pts == NoTimestamp ||
+		pkt.streamIndex < 0 {
+		return
+	}
+
+	tbNum, tbDen := pkt.timeBase()
+	streamIndex := index.stream(pkt.streamIndex)
+
+	if streamIndex == nil {
+		index.Streams = append(index.Streams, StreamIndex{
+			Stream:      pkt.streamIndex,
+			TimeBaseNum: tbNum,
+			TimeBaseDen: tbDen,
+		})
+
+		streamIndex = &index.Streams[len(index.Streams)-1]
+	}
+
+	entries := streamIndex.Entries
+	i := sort.Search(len(entries), func(i int) bool {
+		return entries[i].PTS >= pkt.pts
+	})
+
+	// The packet was already recorded, e.g.
+	// it was read again after seeking.
+	if i < len(entries) && entries[i].PTS == pkt.pts {
+		return
+	}
+
+	entries = append(entries, IndexEntry{})
+	copy(entries[i+1:], entries[i:])
+	entries[i] = IndexEntry{
+		PTS:      pkt.pts,
+		DTS:      pkt.dts,
+		Position: pkt.pos,
+		Size:     pkt.size,
+	}
+
+	streamIndex.Entries = entries
+}
+
+// Keyframe returns the last keyframe of the
+// stream with the PTS not exceeding the
+// specified one.
+func (index *Index) Keyframe(stream int, pts int64) (IndexEntry, bool) {
+	streamIndex := index.stream(stream)
+
+	if streamIndex == nil {
+		return IndexEntry{}, false
+	}
+
+	entries := streamIndex.Entries
+	i := sort.Search(len(entries), func(i int) bool {
+		return entries[i].PTS > pts
+	})
+
+	if i <= 0 {
+		return IndexEntry{}, false
+	}
+
+	return entries[i-1], true
+}
+
+// MarshalBinary encodes the index
+// into the compact binary form.
+func (index *Index) MarshalBinary() ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	buf.Write(indexMagic[:])
+	buf.WriteByte(indexVersion)
+
+	write := func(value interface{}) {
+		// Writing to a bytes.Buffer never fails.
+		binary.Write(buf, binary.LittleEndian, value)
+	}
+
+	write(uint32(len(index.Streams)))
+
+	for _, streamIndex := range index.Streams {
+		write(int32(streamIndex.Stream))
+		write(int32(streamIndex.TimeBaseNum))
+		write(int32(streamIndex.TimeBaseDen))
+		write(uint32(len(streamIndex.Entries)))
+
+		for _, entry := range streamIndex.Entries {
+			write(entry.PTS)
+			write(entry.DTS)
+			write(entry.Position)
+			write(int32(entry.Size))
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes the index
+// encoded with MarshalBinary.
+func (index *Index) UnmarshalBinary(data []byte) error {
+	reader := bytes.NewReader(data)
+
+	var (
+		magic   [4]byte
+		version uint8
+		count   uint32
+	)
+
+	read := func(value interface{}) error {
+		err := binary.Read(reader, binary.LittleEndian, value)
+
+		if err != nil {
+			return fmt.Errorf(
+				"couldn't decode the index: %w", err)
+		}
+
+		return nil
+	}
+
+	err := read(&magic)
+
+	if err != nil {
+		return err
+	}
+
+	if magic != indexMagic {
+		return fmt.Errorf(
+			"couldn't decode the index: invalid signature")
+	}
+
+	err = read(&version)
+
+	if err != nil {
+		return err
+	}
+
+	// The first version had
+	// no decoding timestamps.
+	if version != 1 && version != indexVersion {
+		return fmt.Errorf(
+			"couldn't decode the index: unsupported version %d",
+			version)
+	}
+
+	err = read(&count)
+
+	if err != nil {
+		return err
+	}
+
+	streams := []StreamIndex{}
+
+	for i := uint32(0); i < count; i++ {
+		var header struct {
+			Stream      int32
+			TimeBaseNum int32
+			TimeBaseDen int32
+			Count       uint32
+		}
+
+		err = read(&header)
+
+		if err != nil {
+			return err
+		}
+
+		streamIndex := StreamIndex{
+			Stream:      int(header.Stream),
+			TimeBaseNum: int(header.TimeBaseNum),
+			TimeBaseDen: int(header.TimeBaseDen),
+			Entries:     []IndexEntry{},
+		}
+
+		for j := uint32(0); j < header.Count; j++ {
+			entry := IndexEntry{DTS: NoTimestamp}
+
+			err = read(&entry.PTS)
+
+			if err != nil {
+				return err
+			}
+
+			if version >= 2 {
+				err = read(&entry.DTS)
+
+				if err != nil {
+					return err
+				}
+			}
+
+			var rest struct {
+				Position int64
+				Size     int32
+			}
+
+			err = read(&rest)
+
+			if err != nil {
+				return err
+			}
+
+			entry.Position = rest.Position
+			entry.Size = int(rest.Size)
+			streamIndex.Entries = append(streamIndex.Entries, entry)
+		}
+
+		streams = append(streams, streamIndex)
+	}
+
+	index.Streams = streams
+
+	return nil
+}
+
+// stream returns the index of the
+// stream or nil if there's none.
+func (index *Index) stream(stream int) *StreamIndex {
+	for i := range index.Streams {
+		if index.Streams[i].Stream == stream {
+			return &index.Streams[i]
+		}
+	}
+
+	return nil
+}
+
+// BuildIndex reads all the packets of the
+// media and records the keyframes of all
+// the streams. After that the media is
+// rewound to the start.
+//
+// The media should be opened for decoding
+// with OpenDecode, but its streams don't
+// need to be opened.
+func (media *Media) BuildIndex() (*Index, error) {
+	if !media.seekable() {
+		return nil, fmt.Errorf(
+			"couldn't build the index: the media source is not seekable")
+	}
+
+	index := &Index{Streams: []StreamIndex{}}
+
+	for {
+		pkt, gotPacket, err := media.ReadPacket()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !gotPacket {
+			break
+		}
+
+		if pkt != nil {
+			index.Add(pkt)
+		}
+	}
+
+	err := media.Seek(0, SeekKeyframe)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// UseIndex makes Seek find keyframes with
+// the index instead of the index of the
+// container. Passing nil disables it.
+//
+// The keyframes are also added to the libAV
+// index of the streams which have no index
+// of their own, so the demuxers jump straight
+// to them. They are kept there even if the
+// index is disabled later.
+func (media *Media) UseIndex(index *Index) {
+	media.index = index
+
+	if index != nil {
+		media.addIndexEntries(index)
+	}
+}
+
+// addIndexEntries adds the keyframes of
+// the index to the libAV index of the
+// corresponding streams if they have none.
+func (media *Media) addIndexEntries(index *Index) {
+	for _, streamIndex := range index.Streams {
+		if streamIndex.Stream < 0 ||
+			streamIndex.Stream >= len(media.streams) ||
+			streamIndex.TimeBaseNum <= 0 ||
+			streamIndex.TimeBaseDen <= 0 {
+			continue
+		}
+
+		inner := media.streams[streamIndex.Stream].innerStream()
+
+		// The index of the stream may be the sample
+		// table of the demuxer (e.g. MP4), which
+		// mustn't get the foreign entries.
+		if C.avformat_index_get_entries_count(inner) > 0 &&
+			!media.indexed[streamIndex.Stream] {
+			continue
+		}
+
+		timeBase := C.AVRational{
+			num: C.int(streamIndex.TimeBaseNum),
+			den: C.int(streamIndex.TimeBaseDen),
+		}
+
+		if media.indexed == nil {
+			media.indexed = map[int]bool{}
+		}
+
+		media.indexed[streamIndex.Stream] = true
+
+		for _, entry := range streamIndex.Entries {
+			// libAV can't jump to the keyframe
+			// of unknown position, and its index
+			// is in the decoding order.
+			if entry.Position < 0 || entry.DTS == NoTimestamp {
+				continue
+			}
+
+			// The entries rejected by libAV are
+			// skipped, Seek still finds them.
+			C.av_add_index_entry(inner, C.int64_t(entry.Position),
+				C.av_rescale_q(C.int64_t(entry.DTS), timeBase,
+					inner.time_base), C.int(entry.Size),
+				0, C.AVINDEX_KEYFRAME)
+		}
+	}
+}
+
+// seekIndex positions the media at the
+// keyframe preceding the timestamp (in
+// AV_TIME_BASE units) found in the index.
+//
+// It returns 'false' if the index has
+// no suitable keyframe.
+func (media *Media) seekIndex(ts int64) (bool, error) {
+	stream := -1
+
+	if videoStream, err := media.BestVideoStream(); err == nil {
+		stream = videoStream.Index()
+	} else if len(media.index.Streams) > 0 {
+		stream = media.index.Streams[0].Stream
+	}
+
+	streamIndex := media.index.stream(stream)
+
+	if streamIndex == nil || stream >= len(media.streams) ||
+		streamIndex.TimeBaseNum <= 0 || streamIndex.TimeBaseDen <= 0 {
+		return false, nil
+	}
+
+	timeBase := C.AVRational{
+		num: C.int(streamIndex.TimeBaseNum),
+		den: C.int(streamIndex.TimeBaseDen),
+	}
+	pts := int64(C.av_rescale_q(C.int64_t(ts),
+		C.AVRational{num: 1, den: C.AV_TIME_BASE}, timeBase))
+	entry, ok := media.index.Keyframe(stream, pts)
+
+	if !ok {
+		return false, nil
+	}
+
+	// Seeking by bytes would leave the demuxer
+	// state inconsistent, so the keyframe is
+	// found by its timestamp. The demuxers index
+	// the packets by DTS, and the generic seeking
+	// uses the entries added with UseIndex.
+	ts = entry.PTS
+
+	if entry.DTS != NoTimestamp {
+		ts = entry.DTS
+	}
+
+	target := C.av_rescale_q(C.int64_t(ts), timeBase,
+		media.streams[stream].innerStream().time_base)
+
+	// The seek never goes past the keyframe.
+	status := C.avformat_seek_file(media.ctx, C.int(stream),
+		C.INT64_MIN, target, target, 0)
+
+	if status < 0 {
+		return false, newAVError(status, "avformat_seek_file")
+	}
+
+	// The generic seeking positions the input at
+	// the keyframe packet, so landing elsewhere
+	// means the index doesn't match the media.
+	if media.indexed[stream] && entry.Position >= 0 &&
+		entry.DTS != NoTimestamp && media.ctx.pb != nil &&
+		int64(C.avio_tell(media.ctx.pb)) != entry.Position {
+		return false, nil
+	}
+
+	return true, nil
+}
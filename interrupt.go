@@ -0,0 +1,85 @@
+package reisen
+
+// #cgo pkg-config: libavformat
+// #include <libavformat/avformat.h>
+//
+// extern int reisenInterrupt(void* opaque);
+import "C"
+import (
+	"context"
+	"sync"
+	"unsafe"
+)
+
+// interruptContext holds the Go context
+// the blocking libAV calls of the media
+// are interrupted by.
+type interruptContext struct {
+	mutex  sync.Mutex
+	ctx    context.Context
+	opaque *opaque
+}
+
+// set sets the context for the
+// next blocking libAV call.
+func (interrupt *interruptContext) set(ctx context.Context) {
+	interrupt.mutex.Lock()
+	defer interrupt.mutex.Unlock()
+
+	interrupt.ctx = ctx
+}
+
+// reset removes the context after
+// the blocking libAV call is done.
+func (interrupt *interruptContext) reset() {
+	interrupt.set(nil)
+}
+
+// interrupted returns 'true' if the current
+// context is either cancelled or expired.
+func (interrupt *interruptContext) interrupted() bool {
+	interrupt.mutex.Lock()
+	defer interrupt.mutex.Unlock()
+
+	return interrupt.ctx != nil &&
+		interrupt.ctx.Err() != nil
+}
+
+// install sets the interrupt callback
+// for the media format context.
+func (interrupt *interruptContext) install(ctx *C.AVFormatContext) {
+	ctx.interrupt_callback.callback = (*[0]byte)(
+		unsafe.Pointer(C.reisenInterrupt))
+	ctx.interrupt_callback.opaque = interrupt.opaque.pointer()
+}
+
+// close releases the callback opaque.
+func (interrupt *interruptContext) close() {
+	interrupt.opaque.free()
+}
+
+// newInterruptContext creates a new context
+// for interrupting blocking libAV calls.
+func newInterruptContext() (*interruptContext, error) {
+	interrupt := &interruptContext{}
+	op, err := newOpaque(interrupt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	interrupt.opaque = op
+
+	return interrupt, nil
+}
+
+//export reisenInterrupt
+func reisenInterrupt(ptr unsafe.Pointer) C.int {
+	interrupt := opaqueValue(ptr).(*interruptContext)
+
+	if interrupt.interrupted() {
+		return 1
+	}
+
+	return 0
+}
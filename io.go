@@ -0,0 +1,198 @@
+package reisen
+
+// #cgo pkg-config: libavformat libavutil
+// #include <libavformat/avformat.h>
+// #include <libavutil/mem.h>
+// #include <stdint.h>
+// #include <errno.h>
+//
+// extern int reisenReadPacket(void* opaque, uint8_t* buf, int bufSize);
+// extern int64_t reisenSeek(void* opaque, int64_t offset, int whence);
+import "C"
+import (
+	"fmt"
+	"io"
+	"unsafe"
+)
+
+const (
+	// ioBufferSize is the size of the buffer
+	// used by libAV to read data from the
+	// custom media source.
+	ioBufferSize = 32 * 1024
+)
+
+// ioSource is a Go data source
+// the media container is read from.
+type ioSource struct {
+	reader io.Reader
+	seeker io.Seeker
+	// err is the last error returned
+	// by the reader except io.EOF.
+	err error
+}
+
+// ioContext is a custom libAV I/O context
+// reading the media data from the Go source.
+type ioContext struct {
+	inner  *C.AVIOContext
+	source *ioSource
+	opaque *opaque
+}
+
+// seekable returns 'true' if the
+// underlying source supports seeking.
+func (ioCtx *ioContext) seekable() bool {
+	return ioCtx.source.seeker != nil
+}
+
+// readerError returns the last error of the
+// Go reader and forgets it, so it's not
+// reported for the later failures.
+func (ioCtx *ioContext) readerError() error {
+	err := ioCtx.source.err
+	ioCtx.source.err = nil
+
+	return err
+}
+
+// close frees the memory of the I/O context
+// and releases the Go data source.
+func (ioCtx *ioContext) close() {
+	if ioCtx.inner != nil {
+		C.av_freep(unsafe.Pointer(&ioCtx.inner.buffer))
+		C.avio_context_free(&ioCtx.inner)
+		ioCtx.inner = nil
+	}
+
+	if ioCtx.opaque != nil {
+		ioCtx.opaque.free()
+		ioCtx.opaque = nil
+	}
+}
+
+// newIOContext creates a new custom
+// I/O context for the Go data source.
+func newIOContext(source *ioSource) (*ioContext, error) {
+	op, err := newOpaque(source)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ioCtx := &ioContext{
+		source: source,
+		opaque: op,
+	}
+
+	buf := (*C.uchar)(C.av_malloc(ioBufferSize))
+
+	if buf == nil {
+		ioCtx.close()
+
+		return nil, fmt.Errorf(
+			"couldn't allocate the I/O buffer")
+	}
+
+	var seek *[0]byte
+
+	if source.seeker != nil {
+		seek = (*[0]byte)(unsafe.Pointer(C.reisenSeek))
+	}
+
+	ioCtx.inner = C.avio_alloc_context(buf, ioBufferSize, 0,
+		ioCtx.opaque.pointer(),
+		(*[0]byte)(unsafe.Pointer(C.reisenReadPacket)),
+		nil, seek)
+
+	if ioCtx.inner == nil {
+		C.av_free(unsafe.Pointer(buf))
+		ioCtx.close()
+
+		return nil, fmt.Errorf(
+			"couldn't allocate the I/O context")
+	}
+
+	return ioCtx, nil
+}
+
+// ioSourceFromOpaque returns the Go data source
+// the opaque pointer of the I/O context refers to.
+func ioSourceFromOpaque(ptr unsafe.Pointer) *ioSource {
+	return opaqueValue(ptr).(*ioSource)
+}
+
+//export reisenReadPacket
+func reisenReadPacket(ptr unsafe.Pointer, buf *C.uint8_t, bufSize C.int) C.int {
+	source := ioSourceFromOpaque(ptr)
+	data := unsafe.Slice((*byte)(unsafe.Pointer(buf)), int(bufSize))
+
+	var (
+		n   int
+		err error
+	)
+
+	// io.Reader is allowed to return
+	// no data and no error, so the
+	// read should be repeated.
+	for n == 0 && err == nil {
+		n, err = source.reader.Read(data)
+	}
+
+	if n > 0 {
+		return C.int(n)
+	}
+
+	if err == io.EOF {
+		return C.int(ErrorEndOfFile)
+	}
+
+	// libAV only gets the error code, so
+	// the original error is kept to be
+	// reported by ReadPacket.
+	source.err = err
+
+	return C.int(-C.EIO)
+}
+
+//export reisenSeek
+func reisenSeek(ptr unsafe.Pointer, offset C.int64_t, whence C.int) C.int64_t {
+	source := ioSourceFromOpaque(ptr)
+
+	if source.seeker == nil {
+		return -1
+	}
+
+	// libAV requests the size of the
+	// source without actual seeking.
+	if whence&C.AVSEEK_SIZE != 0 {
+		current, err := source.seeker.Seek(0, io.SeekCurrent)
+
+		if err != nil {
+			return -1
+		}
+
+		size, err := source.seeker.Seek(0, io.SeekEnd)
+
+		if err != nil {
+			return -1
+		}
+
+		_, err = source.seeker.Seek(current, io.SeekStart)
+
+		if err != nil {
+			return -1
+		}
+
+		return C.int64_t(size)
+	}
+
+	whence &^= C.AVSEEK_FORCE
+	position, err := source.seeker.Seek(int64(offset), int(whence))
+
+	if err != nil {
+		return -1
+	}
+
+	return C.int64_t(position)
+}
@@ -9,17 +9,37 @@ package reisen
 import "C"
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"image"
+	"io"
+	"strings"
 	"time"
 	"unsafe"
 )
 
+// creationTimeLayouts are the layouts
+// of the creation_time tag written
+// by the common muxers.
+var creationTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
 // Media is a media file containing
 // audio, video and other types of streams.
 type Media struct {
-	ctx     *C.AVFormatContext
-	packet  *C.AVPacket
-	streams []Stream
+	ctx       *C.AVFormatContext
+	packet    *C.AVPacket
+	streams   []Stream
+	customIO  *ioContext
+	interrupt *interruptContext
+	unused    []string
+	index     *Index
+	indexed   map[int]bool
 }
 
 // StreamCount returns the number of streams.
@@ -38,11 +58,18 @@ func (media *Media) Streams() []Stream {
 
 // VideoStreams returns all the
 // video streams of the media file.
+//
+// The attached pictures (e.g. cover art)
+// are not considered video streams.
+// They can be obtained with CoverArt.
 func (media *Media) VideoStreams() []*VideoStream {
 	videoStreams := []*VideoStream{}
 
 	for _, stream := range media.streams {
-		if videoStream, ok := stream.(*VideoStream); ok {
+		videoStream, ok := stream.(*VideoStream)
+
+		if ok && !videoStream.Disposition().
+			Has(DispositionAttachedPicture) {
 			videoStreams = append(videoStreams, videoStream)
 		}
 	}
@@ -50,6 +77,26 @@ func (media *Media) VideoStreams() []*VideoStream {
 	return videoStreams
 }
 
+// CoverArt decodes the picture attached
+// to the media (e.g. the cover art of
+// an album).
+func (media *Media) CoverArt() (image.Image, error) {
+	for _, stream := range media.streams {
+		videoStream, ok := stream.(*VideoStream)
+
+		if !ok || !videoStream.Disposition().
+			Has(DispositionAttachedPicture) {
+			continue
+		}
+
+		return videoStream.attachedPicture()
+	}
+
+	return nil, fmt.Errorf(
+		"the media has no attached picture: %w",
+		ErrStreamNotFound)
+}
+
 // AudioStreams returns all the
 // audio streams of the media file.
 func (media *Media) AudioStreams() []*AudioStream {
@@ -64,6 +111,153 @@ func (media *Media) AudioStreams() []*AudioStream {
 	return audioStreams
 }
 
+// BestVideoStream returns the video stream
+// the media should be played with according
+// to the stream dispositions and properties.
+func (media *Media) BestVideoStream() (*VideoStream, error) {
+	index, err := media.bestStream(
+		C.AVMEDIA_TYPE_VIDEO, -1, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	videoStream, ok := media.streams[index].(*VideoStream)
+
+	if !ok || videoStream.Disposition().
+		Has(DispositionAttachedPicture) {
+		return nil, fmt.Errorf(
+			"couldn't find a video stream: %w",
+			ErrStreamNotFound)
+	}
+
+	return videoStream, nil
+}
+
+// BestAudioStream returns the audio stream
+// the media should be played with. It's
+// paired with the best video stream if
+// the media has one.
+//
+// If the preferred languages are specified,
+// the streams in these languages are chosen
+// first in the order of preference.
+func (media *Media) BestAudioStream(languages ...string) (*AudioStream, error) {
+	related := -1
+
+	if videoStream, err := media.BestVideoStream(); err == nil {
+		related = videoStream.Index()
+	}
+
+	index, err := media.bestStream(
+		C.AVMEDIA_TYPE_AUDIO, related, languages)
+
+	if err != nil {
+		return nil, err
+	}
+
+	audioStream, ok := media.streams[index].(*AudioStream)
+
+	if !ok {
+		return nil, fmt.Errorf(
+			"couldn't find an audio stream: %w",
+			ErrStreamNotFound)
+	}
+
+	return audioStream, nil
+}
+
+// BestSubtitleStream returns the subtitle
+// stream the media should be played with.
+// It's paired with the best audio stream
+// or with the best video stream.
+//
+// If the preferred languages are specified,
+// the streams in these languages are chosen
+// first in the order of preference.
+func (media *Media) BestSubtitleStream(languages ...string) (Stream, error) {
+	related := -1
+
+	if audioStream, err := media.BestAudioStream(); err == nil {
+		related = audioStream.Index()
+	} else if videoStream, err := media.BestVideoStream(); err == nil {
+		related = videoStream.Index()
+	}
+
+	index, err := media.bestStream(
+		C.AVMEDIA_TYPE_SUBTITLE, related, languages)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return media.streams[index], nil
+}
+
+// bestStream returns the index of the best
+// stream of the type related to the stream
+// with the specified index (-1 for none).
+func (media *Media) bestStream(mediaType C.enum_AVMediaType, related int, languages []string) (int, error) {
+	candidates := media.streams
+
+	// The same as av_find_best_stream does, only
+	// the streams of the program the related
+	// stream belongs to are considered.
+	if related >= 0 {
+		program := C.av_find_program_from_stream(
+			media.ctx, nil, C.int(related))
+
+		if program != nil {
+			candidates = (&Program{
+				media: media,
+				inner: program,
+			}).Streams()
+		}
+	}
+
+	for _, language := range languages {
+		index := -1
+		score := -1
+
+		for _, stream := range candidates {
+			if stream.Type() != StreamType(mediaType) ||
+				!strings.EqualFold(stream.Language(), language) {
+				continue
+			}
+
+			disposition := stream.Disposition()
+			streamScore := 0
+
+			if !disposition.Has(DispositionHearingImpaired) &&
+				!disposition.Has(DispositionVisualImpaired) {
+				streamScore += 2
+			}
+
+			if disposition.Has(DispositionDefault) {
+				streamScore++
+			}
+
+			if streamScore > score {
+				index = stream.Index()
+				score = streamScore
+			}
+		}
+
+		if index >= 0 {
+			return index, nil
+		}
+	}
+
+	status := C.av_find_best_stream(media.ctx,
+		mediaType, -1, C.int(related), nil, 0)
+
+	if status < 0 {
+		return -1, newAVError(status, "av_find_best_stream")
+	}
+
+	return int(status), nil
+}
+
 // Duration returns the overall duration
 // of the media file.
 func (media *Media) Duration() (time.Duration, error) {
@@ -102,6 +296,61 @@ func (media *Media) FormatMIMEType() string {
 	return C.GoString(media.ctx.iformat.mime_type)
 }
 
+// Metadata returns the metadata tags of
+// the media container (e.g. title, artist,
+// album, encoder or creation_time).
+func (media *Media) Metadata() map[string]string {
+	return dictionaryMap(media.ctx.metadata)
+}
+
+// Title returns the title of the
+// media or "" if it's not specified.
+func (media *Media) Title() string {
+	return dictionaryValue(media.ctx.metadata, "title")
+}
+
+// CreationTime returns the time the
+// media was created at according
+// to its creation_time tag.
+func (media *Media) CreationTime() (time.Time, error) {
+	value := dictionaryValue(media.ctx.metadata, "creation_time")
+
+	if value == "" {
+		return time.Time{}, fmt.Errorf(
+			"the media has no creation time")
+	}
+
+	for _, layout := range creationTimeLayouts {
+		tm, err := time.Parse(layout, value)
+
+		if err == nil {
+			return tm, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf(
+		"couldn't parse the creation time %s", value)
+}
+
+// UnusedOptions returns the names of the
+// options passed on opening the media
+// which were not consumed by the demuxer
+// or the protocol.
+func (media *Media) UnusedOptions() []string {
+	unused := make([]string, len(media.unused))
+	copy(unused, media.unused)
+
+	return unused
+}
+
+// seekable returns 'false' if the media
+// is read from a custom source which
+// doesn't support seeking.
+func (media *Media) seekable() bool {
+	return media.customIO == nil ||
+		media.customIO.seekable()
+}
+
 // findStreams retrieves the stream information
 // from the media container.
 func (media *Media) findStreams() error {
@@ -109,8 +358,7 @@ func (media *Media) findStreams() error {
 	status := C.avformat_find_stream_info(media.ctx, nil)
 
 	if status < 0 {
-		return fmt.Errorf(
-			"couldn't find stream information")
+		return newAVError(status, "avformat_find_stream_info")
 	}
 
 	innerStreams := unsafe.Slice(
@@ -181,7 +429,21 @@ func (media *Media) OpenDecode() error {
 }
 
 // ReadPacket reads the next packet from the media stream.
+//
+// Upon reaching the end of the media it returns
+// no packet, 'false' and no error. If reading
+// fails, the libAV error is returned. If the
+// media is read from an io.Reader and it fails,
+// its error is wrapped instead.
+//
+// At the end of the media all the opened streams
+// are flushed, so the frames left in their decoders
+// can be read with ReadFrame until it returns 'false'.
 func (media *Media) ReadPacket() (*Packet, bool, error) {
+	// The last packet is kept until the next
+	// read in case its stream is opened later.
+	C.av_packet_unref(media.packet)
+
 	status := C.av_read_frame(media.ctx, media.packet)
 
 	if status < 0 {
@@ -189,8 +451,41 @@ func (media *Media) ReadPacket() (*Packet, bool, error) {
 			return nil, true, nil
 		}
 
-		// No packets anymore.
-		return nil, false, nil
+		ioFailed := media.ctx.pb != nil && media.ctx.pb.error < 0
+
+		// Some demuxers report the end of file
+		// when the underlying I/O fails.
+		if status == C.AVERROR_EOF && ioFailed {
+			status = media.ctx.pb.error
+		}
+
+		if ioFailed && media.customIO != nil {
+			if readerErr := media.customIO.readerError(); readerErr != nil {
+				return nil, false, fmt.Errorf(
+					"couldn't read the media source: %w", readerErr)
+			}
+		}
+
+		// No packets anymore, so the opened
+		// decoders should yield the frames
+		// they still keep.
+		if status == C.AVERROR_EOF {
+			for _, stream := range media.streams {
+				if !stream.Opened() {
+					continue
+				}
+
+				err := stream.Flush()
+
+				if err != nil {
+					return nil, false, err
+				}
+			}
+
+			return nil, false, nil
+		}
+
+		return nil, false, newAVError(status, "av_read_frame")
 	}
 
 	// Filter the packet if needed.
@@ -206,30 +501,60 @@ func (media *Media) ReadPacket() (*Packet, bool, error) {
 
 		if status < 0 {
 			return nil, false,
-				fmt.Errorf("%d: couldn't reference the packet",
-					status)
+				newAVError(status, "av_packet_ref")
 		}
 
 		status = C.av_bsf_send_packet(filter, packetIn)
 
 		if status < 0 {
 			return nil, false,
-				fmt.Errorf("%d: couldn't send the packet to the filter",
-					status)
+				newAVError(status, "av_bsf_send_packet")
 		}
 
+		C.av_packet_unref(packetOut)
 		status = C.av_bsf_receive_packet(filter, packetOut)
 
 		if status < 0 {
 			return nil, false,
-				fmt.Errorf("%d: couldn't receive the packet from the filter",
-					status)
+				newAVError(status, "av_bsf_receive_packet")
 		}
 
 		outPacket = packetOut
 	}
 
-	return newPacket(media, outPacket), true, nil
+	packet := newPacket(media, outPacket)
+
+	if packetStream.Opened() {
+		err := packetStream.feed(outPacket)
+
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	return packet, true, nil
+}
+
+// ReadPacketContext reads the next packet from
+// the media stream. The read is interrupted
+// once the context is cancelled or expired.
+func (media *Media) ReadPacketContext(ctx context.Context) (*Packet, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	media.interrupt.set(ctx)
+	defer media.interrupt.reset()
+
+	packet, ok, err := media.ReadPacket()
+
+	if packet == nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, false, ctxErr
+		}
+	}
+
+	return packet, ok, err
 }
 
 // CloseDecode closes the media container for decoding.
@@ -242,33 +567,179 @@ func (media *Media) CloseDecode() error {
 
 // Close closes the media container.
 func (media *Media) Close() {
-	C.avformat_free_context(media.ctx)
+	C.avformat_close_input(&media.ctx)
 	media.ctx = nil
+
+	if media.customIO != nil {
+		media.customIO.close()
+		media.customIO = nil
+	}
+
+	if media.interrupt != nil {
+		media.interrupt.close()
+		media.interrupt = nil
+	}
 }
 
 // NewMedia returns a new media container analyzer
 // for the specified media file.
 func NewMedia(filename string) (*Media, error) {
+	return openMedia(context.Background(),
+		filename, OpenOptions{}, nil)
+}
+
+// NewMediaContext returns a new media container
+// analyzer for the specified media file. Opening
+// is interrupted once the context is cancelled
+// or expired.
+func NewMediaContext(ctx context.Context, filename string) (*Media, error) {
+	return openMedia(ctx, filename, OpenOptions{}, nil)
+}
+
+// NewMediaFromReader returns a new media container
+// analyzer reading the media data from the reader.
+//
+// The reader is considered non-seekable,
+// so the media can't be rewound.
+func NewMediaFromReader(reader io.Reader) (*Media, error) {
+	customIO, err := newIOContext(&ioSource{
+		reader: reader,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return openMedia(context.Background(),
+		"", OpenOptions{}, customIO)
+}
+
+// NewMediaFromReadSeeker returns a new media
+// container analyzer reading the media data
+// from the seekable reader.
+func NewMediaFromReadSeeker(reader io.ReadSeeker) (*Media, error) {
+	customIO, err := newIOContext(&ioSource{
+		reader: reader,
+		seeker: reader,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return openMedia(context.Background(),
+		"", OpenOptions{}, customIO)
+}
+
+// NewMediaFromBytes returns a new media
+// container analyzer for the media data
+// kept in memory.
+//
+// The slice is referenced by the media until
+// it's closed, so it shouldn't be modified.
+func NewMediaFromBytes(data []byte) (*Media, error) {
+	return NewMediaFromReadSeeker(bytes.NewReader(data))
+}
+
+// NewMediaWithOptions returns a new media
+// container analyzer for the specified URL
+// opened with the demuxer and protocol options.
+//
+// The options not recognized by the demuxer
+// can be obtained with UnusedOptions().
+func NewMediaWithOptions(url string, opts OpenOptions) (*Media, error) {
+	return openMedia(context.Background(),
+		url, opts, nil)
+}
+
+// openMedia opens the media container by the
+// file name or by the custom I/O context
+// if it's not nil.
+func openMedia(ctx context.Context, filename string, opts OpenOptions, customIO *ioContext) (*Media, error) {
+	if err := ctx.Err(); err != nil {
+		if customIO != nil {
+			customIO.close()
+		}
+
+		return nil, err
+	}
+
 	media := &Media{
-		ctx: C.avformat_alloc_context(),
+		customIO: customIO,
 	}
 
+	format, err := opts.inputFormat()
+
+	if err != nil {
+		media.Close()
+		return nil, err
+	}
+
+	options, err := newDictionary(opts.Options)
+
+	if err != nil {
+		media.Close()
+		return nil, err
+	}
+
+	defer C.av_dict_free(&options)
+
+	media.interrupt, err = newInterruptContext()
+
+	if err != nil {
+		media.Close()
+		return nil, err
+	}
+
+	media.ctx = C.avformat_alloc_context()
+
 	if media.ctx == nil {
+		media.Close()
+
 		return nil, fmt.Errorf(
 			"couldn't create a new media context")
 	}
 
+	if customIO != nil {
+		media.ctx.pb = customIO.inner
+	}
+
+	media.interrupt.install(media.ctx)
+	media.interrupt.set(ctx)
+	defer media.interrupt.reset()
+
 	fname := C.CString(filename)
-	status := C.avformat_open_input(&media.ctx, fname, nil, nil)
+	status := C.avformat_open_input(&media.ctx, fname, format, &options)
+	C.free(unsafe.Pointer(fname))
 
 	if status < 0 {
-		return nil, fmt.Errorf(
-			"couldn't open file %s", filename)
+		// The format context is freed
+		// by libAV on failure.
+		media.ctx = nil
+		media.Close()
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if filename == "" {
+			return nil, newAVError(status, "avformat_open_input")
+		}
+
+		return nil, fmt.Errorf("couldn't open file %s: %w",
+			filename, newAVError(status, "avformat_open_input"))
 	}
 
-	C.free(unsafe.Pointer(fname))
-	err := media.findStreams()
+	media.unused = dictionaryKeys(options)
+	err = media.findStreams()
+
 	if err != nil {
+		media.Close()
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+
 		return nil, err
 	}
 
@@ -2,13 +2,12 @@ package reisen
 
 // #include <libavformat/avformat.h>
 import "C"
-import "fmt"
 
 func NetworkInitialize() error {
 	code := C.avformat_network_init()
 
 	if code < 0 {
-		return fmt.Errorf("error occurred: 0x%X", code)
+		return newAVError(code, "avformat_network_init")
 	}
 
 	return nil
@@ -18,7 +17,7 @@ func NetworkDeinitialize() error {
 	code := C.avformat_network_deinit()
 
 	if code < 0 {
-		return fmt.Errorf("error occurred: 0x%X", code)
+		return newAVError(code, "avformat_network_deinit")
 	}
 
 	return nil
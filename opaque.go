@@ -0,0 +1,68 @@
+package reisen
+
+// #cgo pkg-config: libavutil
+// #include <libavutil/mem.h>
+// #include <stdint.h>
+import "C"
+import (
+	"fmt"
+	"runtime/cgo"
+	"unsafe"
+)
+
+// opaque is a reference to a Go value
+// passed to the libAV callbacks.
+//
+// The handle is kept in the C memory
+// because libAV holds the opaque pointer
+// for the whole lifetime of its context.
+type opaque struct {
+	handle cgo.Handle
+	ptr    *C.uintptr_t
+}
+
+// pointer returns the pointer to be
+// passed to libAV as a callback opaque.
+func (op *opaque) pointer() unsafe.Pointer {
+	return unsafe.Pointer(op.ptr)
+}
+
+// free releases the Go value and
+// frees the memory of the opaque.
+func (op *opaque) free() {
+	if op.ptr == nil {
+		return
+	}
+
+	C.av_free(unsafe.Pointer(op.ptr))
+	op.ptr = nil
+	op.handle.Delete()
+}
+
+// newOpaque creates a new callback
+// opaque referencing the Go value.
+func newOpaque(value interface{}) (*opaque, error) {
+	ptr := (*C.uintptr_t)(C.av_malloc(
+		C.size_t(C.sizeof_uintptr_t)))
+
+	if ptr == nil {
+		return nil, fmt.Errorf(
+			"couldn't allocate the callback opaque")
+	}
+
+	op := &opaque{
+		handle: cgo.NewHandle(value),
+		ptr:    ptr,
+	}
+
+	*op.ptr = C.uintptr_t(op.handle)
+
+	return op, nil
+}
+
+// opaqueValue returns the Go value
+// the callback opaque refers to.
+func opaqueValue(ptr unsafe.Pointer) interface{} {
+	handle := cgo.Handle(*(*C.uintptr_t)(ptr))
+	return handle.Value()
+}
@@ -0,0 +1,136 @@
+package reisen
+
+// #cgo pkg-config: libavformat libavutil
+// #include <libavformat/avformat.h>
+// #include <libavutil/dict.h>
+// #include <stdlib.h>
+import "C"
+import (
+	"fmt"
+	"sort"
+	"unsafe"
+)
+
+// OpenOptions holds the parameters
+// for opening the media container.
+type OpenOptions struct {
+	// Format is the short name of the demuxer
+	// to be used instead of probing the media
+	// format (e.g. "h264", "s16le", "mpegts").
+	Format string
+	// Options contains demuxer and protocol
+	// options like "probesize", "analyzeduration",
+	// "fflags", "rtsp_transport" or "timeout".
+	Options map[string]string
+}
+
+// inputFormat returns the demuxer forced
+// by the options or nil if the format
+// should be probed.
+func (opts OpenOptions) inputFormat() (*C.AVInputFormat, error) {
+	if opts.Format == "" {
+		return nil, nil
+	}
+
+	name := C.CString(opts.Format)
+	defer C.free(unsafe.Pointer(name))
+
+	format := C.av_find_input_format(name)
+
+	if format == nil {
+		return nil, fmt.Errorf("couldn't find the input format %s: %w",
+			opts.Format, ErrDemuxerNotFound)
+	}
+
+	return format, nil
+}
+
+// newDictionary creates a new libAV
+// dictionary out of the Go map.
+//
+// The dictionary should be freed
+// with av_dict_free afterwards.
+func newDictionary(entries map[string]string) (*C.AVDictionary, error) {
+	var dict *C.AVDictionary
+
+	keys := make([]string, 0, len(entries))
+
+	for key := range entries {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		cKey := C.CString(key)
+		cValue := C.CString(entries[key])
+		status := C.av_dict_set(&dict, cKey, cValue, 0)
+
+		C.free(unsafe.Pointer(cKey))
+		C.free(unsafe.Pointer(cValue))
+
+		if status < 0 {
+			C.av_dict_free(&dict)
+
+			return nil, newAVError(status, "av_dict_set")
+		}
+	}
+
+	return dict, nil
+}
+
+// dictionaryMap returns the
+// libAV dictionary as a Go map.
+func dictionaryMap(dict *C.AVDictionary) map[string]string {
+	entries := map[string]string{}
+	empty := C.CString("")
+	defer C.free(unsafe.Pointer(empty))
+
+	var entry *C.AVDictionaryEntry
+
+	for {
+		entry = C.av_dict_get(dict, empty,
+			entry, C.AV_DICT_IGNORE_SUFFIX)
+
+		if entry == nil {
+			break
+		}
+
+		entries[C.GoString(entry.key)] =
+			C.GoString(entry.value)
+	}
+
+	return entries
+}
+
+// dictionaryValue returns the value of
+// the libAV dictionary entry matching the
+// key case-insensitively or "" if there's
+// no such entry.
+func dictionaryValue(dict *C.AVDictionary, key string) string {
+	cKey := C.CString(key)
+	defer C.free(unsafe.Pointer(cKey))
+
+	entry := C.av_dict_get(dict, cKey, nil, 0)
+
+	if entry == nil {
+		return ""
+	}
+
+	return C.GoString(entry.value)
+}
+
+// dictionaryKeys returns the sorted
+// keys of the libAV dictionary.
+func dictionaryKeys(dict *C.AVDictionary) []string {
+	entries := dictionaryMap(dict)
+	keys := make([]string, 0, len(entries))
+
+	for key := range entries {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -6,7 +6,11 @@ package reisen
 // #include <libavutil/avconfig.h>
 // #include <libswscale/swscale.h>
 import "C"
-import "unsafe"
+import (
+	"fmt"
+	"time"
+	"unsafe"
+)
 
 // Packet is a piece of encoded data
 // acquired from the media container.
@@ -23,6 +27,7 @@ type Packet struct {
 	duration    int64
 	size        int
 	flags       int
+	sideData    []SideData
 }
 
 // StreamIndex returns the index of the
@@ -34,6 +39,10 @@ func (pkt *Packet) StreamIndex() int {
 // Type returns the type of the packet
 // (video or audio).
 func (pkt *Packet) Type() StreamType {
+	if pkt.media == nil {
+		return StreamType(C.AVMEDIA_TYPE_UNKNOWN)
+	}
+
 	return pkt.media.Streams()[pkt.streamIndex].Type()
 }
 
@@ -53,6 +62,216 @@ func (pkt *Packet) Size() int {
 	return pkt.size
 }
 
+// PTS returns the presentation timestamp of
+// the packet in the time base units of the
+// stream or NoTimestamp if it's unknown.
+func (pkt *Packet) PTS() int64 {
+	return pkt.pts
+}
+
+// DTS returns the decompression timestamp of
+// the packet in the time base units of the
+// stream or NoTimestamp if it's unknown.
+func (pkt *Packet) DTS() int64 {
+	return pkt.dts
+}
+
+// RawDuration returns the duration of the
+// packet in the time base units of the
+// stream or 0 if it's unknown.
+func (pkt *Packet) RawDuration() int64 {
+	return pkt.duration
+}
+
+// Position returns the byte position of
+// the packet in the media or -1 if
+// it's unknown.
+func (pkt *Packet) Position() int64 {
+	return pkt.pos
+}
+
+// Flags returns the libAV
+// flags of the packet.
+func (pkt *Packet) Flags() int {
+	return pkt.flags
+}
+
+// IsKeyframe returns 'true' if the
+// packet contains a keyframe.
+func (pkt *Packet) IsKeyframe() bool {
+	return pkt.flags&C.AV_PKT_FLAG_KEY != 0
+}
+
+// IsCorrupt returns 'true' if the
+// packet data is corrupted.
+func (pkt *Packet) IsCorrupt() bool {
+	return pkt.flags&C.AV_PKT_FLAG_CORRUPT != 0
+}
+
+// IsDiscard returns 'true' if the packet
+// is required only to maintain the decoder
+// state and its frame should be discarded.
+func (pkt *Packet) IsDiscard() bool {
+	return pkt.flags&C.AV_PKT_FLAG_DISCARD != 0
+}
+
+// IsDisposable returns 'true' if the packet
+// contains a frame no other frame depends on.
+func (pkt *Packet) IsDisposable() bool {
+	return pkt.flags&C.AV_PKT_FLAG_DISPOSABLE != 0
+}
+
+// SideData returns the side data
+// attached to the packet.
+func (pkt *Packet) SideData() []SideData {
+	sideData := make([]SideData, len(pkt.sideData))
+
+	for i, entry := range pkt.sideData {
+		sideData[i] = SideData{
+			Type: entry.Type,
+			Data: make([]byte, len(entry.Data)),
+		}
+
+		copy(sideData[i].Data, entry.Data)
+	}
+
+	return sideData
+}
+
+// SideDataOfType returns the side data
+// of the specified type attached to the
+// packet and 'false' if there's none.
+func (pkt *Packet) SideDataOfType(sideDataType SideDataType) (SideData, bool) {
+	for _, entry := range pkt.sideData {
+		if entry.Type == sideDataType {
+			data := make([]byte, len(entry.Data))
+			copy(data, entry.Data)
+
+			return SideData{Type: entry.Type, Data: data}, true
+		}
+	}
+
+	return SideData{}, false
+}
+
+// PresentationTime returns the time offset
+// since the start of the media at which the
+// packet should be presented.
+func (pkt *Packet) PresentationTime() (time.Duration, error) {
+	if pkt.pts == NoTimestamp {
+		return 0, fmt.Errorf(
+			"the packet has no presentation timestamp")
+	}
+
+	return pkt.toDuration(pkt.pts)
+}
+
+// DecodeTime returns the time offset
+// since the start of the media at which
+// the packet should be decoded.
+func (pkt *Packet) DecodeTime() (time.Duration, error) {
+	if pkt.dts == NoTimestamp {
+		return 0, fmt.Errorf(
+			"the packet has no decompression timestamp")
+	}
+
+	return pkt.toDuration(pkt.dts)
+}
+
+// Duration returns the time
+// duration of the packet.
+func (pkt *Packet) Duration() (time.Duration, error) {
+	return pkt.toDuration(pkt.duration)
+}
+
+// timeBase returns the time base
+// of the stream the packet belongs to.
+//
+// The packets not read from the media
+// have timestamps in microseconds.
+func (pkt *Packet) timeBase() (int, int) {
+	if pkt.media == nil || pkt.streamIndex < 0 {
+		return 1, TimeBase
+	}
+
+	return pkt.media.streams[pkt.streamIndex].TimeBase()
+}
+
+// toDuration converts the value in the
+// time base units of the packet stream
+// into the time duration.
+func (pkt *Packet) toDuration(value int64) (time.Duration, error) {
+	tbNum, tbDen := pkt.timeBase()
+	tb := float64(tbNum) / float64(tbDen)
+	tm := float64(value) * tb
+
+	return time.ParseDuration(fmt.Sprintf("%fs", tm))
+}
+
+// fill copies the packet data and
+// properties to the libAV packet.
+func (pkt *Packet) fill(cPkt *C.AVPacket) error {
+	status := C.av_new_packet(cPkt, C.int(len(pkt.data)))
+
+	if status < 0 {
+		return newAVError(status, "av_new_packet")
+	}
+
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(
+		cPkt.data)), len(pkt.data)), pkt.data)
+
+	cPkt.stream_index = C.int(pkt.streamIndex)
+	cPkt.pts = C.int64_t(pkt.pts)
+	cPkt.dts = C.int64_t(pkt.dts)
+	cPkt.pos = C.int64_t(pkt.pos)
+	cPkt.duration = C.int64_t(pkt.duration)
+	cPkt.flags = C.int(pkt.flags)
+
+	for _, entry := range pkt.sideData {
+		data := C.av_packet_new_side_data(cPkt,
+			C.enum_AVPacketSideDataType(entry.Type),
+			C.int(len(entry.Data)))
+
+		if data == nil {
+			C.av_packet_unref(cPkt)
+
+			return fmt.Errorf(
+				"couldn't allocate the packet side data")
+		}
+
+		copy(unsafe.Slice((*byte)(unsafe.Pointer(
+			data)), len(entry.Data)), entry.Data)
+	}
+
+	return nil
+}
+
+// NewPacket creates a new packet out of the
+// encoded data obtained from a custom source.
+// Such a packet can be decoded with Decoder.
+//
+// The timestamps are in the time base units of
+// the decoder. NoTimestamp should be used if
+// the timestamp is unknown.
+func NewPacket(data []byte, pts, dts int64, keyframe bool) *Packet {
+	pkt := &Packet{
+		streamIndex: -1,
+		data:        make([]byte, len(data)),
+		pts:         pts,
+		dts:         dts,
+		pos:         -1,
+		size:        len(data),
+	}
+
+	copy(pkt.data, data)
+
+	if keyframe {
+		pkt.flags |= C.AV_PKT_FLAG_KEY
+	}
+
+	return pkt
+}
+
 // newPacket creates a
 // new packet info object.
 func newPacket(media *Media, cPkt *C.AVPacket) *Packet {
@@ -67,6 +286,7 @@ func newPacket(media *Media, cPkt *C.AVPacket) *Packet {
 		duration: int64(cPkt.duration),
 		size:     int(cPkt.size),
 		flags:    int(cPkt.flags),
+		sideData: newSideData(cPkt),
 	}
 
 	return pkt
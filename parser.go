@@ -0,0 +1,217 @@
+package reisen
+
+// #cgo pkg-config: libavcodec libavutil
+// #include <libavcodec/avcodec.h>
+// #include <libavutil/mem.h>
+// #include <stdlib.h>
+import "C"
+import (
+	"fmt"
+	"unsafe"
+)
+
+// Parser splits a raw elementary stream
+// (e.g. Annex B H.264 or HEVC, ADTS AAC)
+// into packets which can be decoded with
+// the decoder of the same codec.
+type Parser struct {
+	ctx      *C.AVCodecParserContext
+	codecCtx *C.AVCodecContext
+	buffer   *C.uint8_t
+	bufSize  int
+	position int64
+}
+
+// Parse splits the next chunk of the byte
+// stream into packets. The data of an
+// incomplete packet is kept inside the
+// parser until the next chunk arrives.
+//
+// The timestamps are assigned to the first
+// packet starting in the chunk. NoTimestamp
+// should be used if they are unknown.
+func (parser *Parser) Parse(data []byte, pts, dts int64) ([]*Packet, error) {
+	packets := []*Packet{}
+
+	if len(data) == 0 {
+		return packets, nil
+	}
+
+	err := parser.reserve(len(data))
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The padding after the data must be zero, but
+	// it may hold the rest of the previous chunk.
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(parser.buffer)),
+		len(data)+C.AV_INPUT_BUFFER_PADDING_SIZE)
+	copy(buf, data)
+
+	for i := len(data); i < len(buf); i++ {
+		buf[i] = 0
+	}
+
+	offset := 0
+
+	for offset < len(data) {
+		packet, used, err := parser.parse(
+			(*C.uint8_t)(unsafe.Add(unsafe.Pointer(
+				parser.buffer), offset)),
+			len(data)-offset, pts, dts)
+
+		if err != nil {
+			return packets, err
+		}
+
+		// The parser neither consumed
+		// the data nor yielded a packet,
+		// so it would never finish.
+		if packet == nil && used <= 0 {
+			return packets, fmt.Errorf(
+				"couldn't parse the data: the parser is stuck at the offset %d",
+				offset)
+		}
+
+		if packet != nil {
+			packets = append(packets, packet)
+		}
+
+		offset += used
+		pts = NoTimestamp
+		dts = NoTimestamp
+	}
+
+	return packets, nil
+}
+
+// Flush returns the last packet kept
+// inside the parser at the end of
+// the byte stream.
+func (parser *Parser) Flush() ([]*Packet, error) {
+	packets := []*Packet{}
+	packet, _, err := parser.parse(nil, 0,
+		NoTimestamp, NoTimestamp)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if packet != nil {
+		packets = append(packets, packet)
+	}
+
+	return packets, nil
+}
+
+// Close closes the parser
+// and frees its memory.
+func (parser *Parser) Close() {
+	C.av_parser_close(parser.ctx)
+	parser.ctx = nil
+	C.avcodec_free_context(&parser.codecCtx)
+	C.av_freep(unsafe.Pointer(&parser.buffer))
+	parser.bufSize = 0
+}
+
+// parse passes the data to the libAV parser
+// and returns the packet completed by it
+// and the number of bytes consumed.
+func (parser *Parser) parse(buf *C.uint8_t, size int, pts, dts int64) (*Packet, int, error) {
+	var (
+		out     *C.uint8_t
+		outSize C.int
+	)
+
+	used := C.av_parser_parse2(parser.ctx, parser.codecCtx,
+		&out, &outSize, buf, C.int(size), C.int64_t(pts),
+		C.int64_t(dts), C.int64_t(parser.position))
+
+	if used < 0 {
+		return nil, 0, newAVError(used, "av_parser_parse2")
+	}
+
+	// The position of the next data
+	// in the whole byte stream.
+	parser.position += int64(used)
+
+	if outSize <= 0 {
+		return nil, int(used), nil
+	}
+
+	packet := &Packet{
+		streamIndex: -1,
+		data:        C.GoBytes(unsafe.Pointer(out), outSize),
+		pts:         int64(parser.ctx.pts),
+		dts:         int64(parser.ctx.dts),
+		pos:         int64(parser.ctx.pos),
+		duration:    int64(parser.ctx.duration),
+		size:        int(outSize),
+	}
+
+	if parser.ctx.key_frame == 1 {
+		packet.flags |= C.AV_PKT_FLAG_KEY
+	}
+
+	return packet, int(used), nil
+}
+
+// reserve makes sure the input buffer can
+// hold the data of the specified size with
+// the padding required by libAV.
+func (parser *Parser) reserve(size int) error {
+	if size <= parser.bufSize {
+		return nil
+	}
+
+	C.av_freep(unsafe.Pointer(&parser.buffer))
+	parser.bufSize = 0
+
+	parser.buffer = (*C.uint8_t)(C.av_mallocz(
+		C.size_t(size + C.AV_INPUT_BUFFER_PADDING_SIZE)))
+
+	if parser.buffer == nil {
+		return fmt.Errorf(
+			"couldn't allocate the parser buffer")
+	}
+
+	parser.bufSize = size
+
+	return nil
+}
+
+// NewParser creates a new parser for the
+// byte stream encoded with the codec
+// (e.g. "h264", "hevc" or "aac").
+func NewParser(codecName string) (*Parser, error) {
+	name := C.CString(codecName)
+	codec := C.avcodec_find_decoder_by_name(name)
+	C.free(unsafe.Pointer(name))
+
+	if codec == nil {
+		return nil, fmt.Errorf(
+			"couldn't find the decoder %s: %w",
+			codecName, ErrDecoderNotFound)
+	}
+
+	parser := &Parser{
+		ctx: C.av_parser_init(C.int(codec.id)),
+	}
+
+	if parser.ctx == nil {
+		return nil, fmt.Errorf(
+			"couldn't find the parser for the codec %s", codecName)
+	}
+
+	parser.codecCtx = C.avcodec_alloc_context3(codec)
+
+	if parser.codecCtx == nil {
+		C.av_parser_close(parser.ctx)
+
+		return nil, fmt.Errorf(
+			"couldn't allocate a codec context")
+	}
+
+	return parser, nil
+}
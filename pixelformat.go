@@ -0,0 +1,119 @@
+package reisen
+
+// #cgo pkg-config: libavutil
+// #include <libavutil/pixdesc.h>
+// #include <libavutil/imgutils.h>
+import "C"
+import "fmt"
+
+// PixelFormat is a format of
+// the video frame pixels.
+type PixelFormat int
+
+const (
+	// PixelFormatNative means the frames are
+	// returned in the format they were decoded
+	// in, without conversion and resizing.
+	PixelFormatNative PixelFormat = C.AV_PIX_FMT_NONE
+	// PixelFormatRGBA is packed RGBA,
+	// 32 bits per pixel.
+	PixelFormatRGBA PixelFormat = C.AV_PIX_FMT_RGBA
+	// PixelFormatBGRA is packed BGRA,
+	// 32 bits per pixel.
+	PixelFormatBGRA PixelFormat = C.AV_PIX_FMT_BGRA
+	// PixelFormatRGB24 is packed RGB,
+	// 24 bits per pixel.
+	PixelFormatRGB24 PixelFormat = C.AV_PIX_FMT_RGB24
+	// PixelFormatBGR24 is packed BGR,
+	// 24 bits per pixel.
+	PixelFormatBGR24 PixelFormat = C.AV_PIX_FMT_BGR24
+	// PixelFormatGray8 is 8 bits
+	// per pixel grayscale.
+	PixelFormatGray8 PixelFormat = C.AV_PIX_FMT_GRAY8
+	// PixelFormatYUV420P is planar YUV
+	// with 2x2 chroma subsampling.
+	PixelFormatYUV420P PixelFormat = C.AV_PIX_FMT_YUV420P
+	// PixelFormatYUV422P is planar YUV
+	// with 2x1 chroma subsampling.
+	PixelFormatYUV422P PixelFormat = C.AV_PIX_FMT_YUV422P
+	// PixelFormatYUV444P is planar YUV
+	// without chroma subsampling.
+	PixelFormatYUV444P PixelFormat = C.AV_PIX_FMT_YUV444P
+	// PixelFormatYUVJ420P is full range
+	// planar YUV with 2x2 chroma subsampling.
+	PixelFormatYUVJ420P PixelFormat = C.AV_PIX_FMT_YUVJ420P
+	// PixelFormatNV12 is YUV with the Y plane
+	// and the interleaved UV plane with 2x2
+	// chroma subsampling.
+	PixelFormatNV12 PixelFormat = C.AV_PIX_FMT_NV12
+)
+
+// String returns the libAV
+// name of the pixel format.
+func (format PixelFormat) String() string {
+	if format == PixelFormatNative {
+		return "native"
+	}
+
+	name := C.av_get_pix_fmt_name(C.enum_AVPixelFormat(format))
+
+	if name == nil {
+		return ""
+	}
+
+	return C.GoString(name)
+}
+
+// planeLayout returns the strides and the heights
+// of the planes of the image of the specified size
+// stored without padding between the rows.
+func (format PixelFormat) planeLayout(width, height int) ([]int, []int, error) {
+	desc := C.av_pix_fmt_desc_get(C.enum_AVPixelFormat(format))
+
+	if desc == nil {
+		return nil, nil, fmt.Errorf(
+			"unknown pixel format %d: %w",
+			int(format), ErrInvalidValue)
+	}
+
+	var linesizes [4]C.int
+
+	status := C.av_image_fill_linesizes(&linesizes[0],
+		C.enum_AVPixelFormat(format), C.int(width))
+
+	if status < 0 {
+		return nil, nil, newAVError(status, "av_image_fill_linesizes")
+	}
+
+	planeCount := int(C.av_pix_fmt_count_planes(
+		C.enum_AVPixelFormat(format)))
+
+	if planeCount < 0 {
+		return nil, nil, newAVError(C.int(planeCount),
+			"av_pix_fmt_count_planes")
+	}
+
+	// The chroma planes are subsampled
+	// vertically, the alpha plane is not.
+	chromaHeight := -((-height) >> desc.log2_chroma_h)
+	strides := make([]int, planeCount)
+	heights := make([]int, planeCount)
+
+	for i := 0; i < planeCount; i++ {
+		strides[i] = int(linesizes[i])
+		heights[i] = height
+
+		if i == 1 || i == 2 {
+			heights[i] = chromaHeight
+		}
+	}
+
+	// The palette of 256 colors is
+	// stored right after the indices.
+	if desc.flags&C.AV_PIX_FMT_FLAG_PAL != 0 {
+		strides = append(strides, 256*4)
+		heights = append(heights, 1)
+	}
+
+	return strides, heights, nil
+}
@@ -0,0 +1,161 @@
+package reisen
+
+// #cgo pkg-config: libavformat libavutil
+// #include <libavformat/avformat.h>
+import "C"
+import "unsafe"
+
+// Program is a group of streams
+// forming a single channel of the
+// multi-program media (e.g. a broadcast
+// MPEG-TS stream).
+type Program struct {
+	media *Media
+	inner *C.AVProgram
+}
+
+// ID returns the identifier
+// of the program.
+func (program *Program) ID() int {
+	return int(program.inner.id)
+}
+
+// Number returns the
+// program number.
+func (program *Program) Number() int {
+	return int(program.inner.program_num)
+}
+
+// PMTPID returns the PID of the program
+// map table of the program.
+func (program *Program) PMTPID() int {
+	return int(program.inner.pmt_pid)
+}
+
+// PCRPID returns the PID carrying the
+// program clock reference of the program.
+func (program *Program) PCRPID() int {
+	return int(program.inner.pcr_pid)
+}
+
+// Metadata returns the metadata
+// tags of the program.
+func (program *Program) Metadata() map[string]string {
+	return dictionaryMap(program.inner.metadata)
+}
+
+// ServiceName returns the name of
+// the service broadcasting the program
+// or "" if it's not specified.
+func (program *Program) ServiceName() string {
+	return dictionaryValue(program.inner.metadata, "service_name")
+}
+
+// ServiceProvider returns the name of the
+// service provider of the program or ""
+// if it's not specified.
+func (program *Program) ServiceProvider() string {
+	return dictionaryValue(program.inner.metadata, "service_provider")
+}
+
+// Streams returns the streams
+// the program consists of.
+func (program *Program) Streams() []Stream {
+	streams := []Stream{}
+
+	for _, index := range program.streamIndexes() {
+		if int(index) < len(program.media.streams) {
+			streams = append(streams,
+				program.media.streams[index])
+		}
+	}
+
+	return streams
+}
+
+// Select makes the demuxer read only the
+// packets of the program streams, so the
+// packets of the other programs are not
+// returned by ReadPacket.
+//
+// Another program can be selected later
+// to switch the channel.
+func (program *Program) Select() {
+	members := map[C.uint]bool{}
+
+	for _, index := range program.streamIndexes() {
+		members[index] = true
+	}
+
+	for _, other := range program.media.programs() {
+		other.discard = C.AVDISCARD_ALL
+	}
+
+	program.inner.discard = C.AVDISCARD_DEFAULT
+
+	for _, stream := range program.media.streams {
+		if members[C.uint(stream.Index())] {
+			stream.innerStream().discard = C.AVDISCARD_DEFAULT
+		} else {
+			stream.innerStream().discard = C.AVDISCARD_ALL
+		}
+	}
+}
+
+// Open selects the program and opens
+// all its streams for decoding.
+func (program *Program) Open() error {
+	program.Select()
+
+	for _, stream := range program.Streams() {
+		if stream.Opened() {
+			continue
+		}
+
+		err := stream.Open()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// streamIndexes returns the indexes
+// of the program streams.
+func (program *Program) streamIndexes() []C.uint {
+	if program.inner.stream_index == nil ||
+		program.inner.nb_stream_indexes <= 0 {
+		return nil
+	}
+
+	return unsafe.Slice(program.inner.stream_index,
+		program.inner.nb_stream_indexes)
+}
+
+// Programs returns the programs
+// the media consists of.
+func (media *Media) Programs() []*Program {
+	programs := []*Program{}
+
+	for _, inner := range media.programs() {
+		programs = append(programs, &Program{
+			media: media,
+			inner: inner,
+		})
+	}
+
+	return programs
+}
+
+// programs returns the libAV
+// programs of the media.
+func (media *Media) programs() []*C.AVProgram {
+	if media.ctx.programs == nil || media.ctx.nb_programs <= 0 {
+		return nil
+	}
+
+	return unsafe.Slice(media.ctx.programs,
+		media.ctx.nb_programs)
+}
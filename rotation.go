@@ -0,0 +1,233 @@
+package reisen
+
+// #cgo pkg-config: libavutil libavformat
+// #include <libavformat/avformat.h>
+// #include <libavutil/display.h>
+// #include <libavutil/pixdesc.h>
+import "C"
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// Rotation returns the clockwise angle in
+// degrees (0, 90, 180 or 270) the frames of
+// the stream should be rotated by to be
+// displayed upright and whether they should
+// be mirrored horizontally before that.
+//
+// It's taken from the display matrix of the
+// stream or from its 'rotate' metadata tag.
+func (video *VideoStream) Rotation() (int, bool) {
+	// The stream of a standalone decoder
+	// is not bound to the media.
+	if video.inner == nil {
+		return 0, false
+	}
+
+	var size C.int
+
+	data := C.av_stream_get_side_data(video.inner,
+		C.AV_PKT_DATA_DISPLAYMATRIX, &size)
+
+	if data != nil && size > 0 {
+		matrix, err := SideData{
+			Type: SideDataDisplayMatrix,
+			Data: C.GoBytes(unsafe.Pointer(data), size),
+		}.DisplayMatrix()
+
+		if err == nil {
+			return displayRotation(matrix)
+		}
+	}
+
+	value := strings.TrimSpace(
+		dictionaryValue(video.inner.metadata, "rotate"))
+
+	if value == "" {
+		return 0, false
+	}
+
+	angle, err := strconv.ParseFloat(value, 64)
+
+	if err != nil {
+		return 0, false
+	}
+
+	return normalizeRotation(angle), false
+}
+
+// displayRotation returns the clockwise
+// rotation and the horizontal flip
+// described by the display matrix.
+func displayRotation(matrix [9]int32) (int, bool) {
+	// The negative determinant means the
+	// picture is mirrored. The mirroring is
+	// removed from the matrix to get the
+	// rotation angle right.
+	flip := int64(matrix[0])*int64(matrix[4])-
+		int64(matrix[1])*int64(matrix[3]) < 0
+
+	if flip {
+		matrix[0] = -matrix[0]
+		matrix[3] = -matrix[3]
+		matrix[6] = -matrix[6]
+	}
+
+	// The angle is counterclockwise.
+	angle := float64(C.av_display_rotation_get(
+		(*C.int32_t)(unsafe.Pointer(&matrix[0]))))
+
+	if math.IsNaN(angle) {
+		return 0, flip
+	}
+
+	return normalizeRotation(-angle), flip
+}
+
+// normalizeRotation rounds the angle in
+// degrees to the nearest right angle
+// in the range [0, 360).
+func normalizeRotation(angle float64) int {
+	quarters := int(math.Round(angle/90)) % 4
+
+	if quarters < 0 {
+		quarters += 4
+	}
+
+	return quarters * 90
+}
+
+// checkRotation returns an error if the
+// planes of the pixel format can't be
+// rotated by the angle.
+func checkRotation(format PixelFormat, degrees int) error {
+	desc := C.av_pix_fmt_desc_get(C.enum_AVPixelFormat(format))
+
+	if desc == nil {
+		return fmt.Errorf(
+			"unknown pixel format %d: %w",
+			int(format), ErrInvalidValue)
+	}
+
+	// The transposed chroma planes would
+	// have another subsampling.
+	if degrees%180 != 0 &&
+		desc.log2_chroma_w != desc.log2_chroma_h {
+		return fmt.Errorf(
+			"couldn't rotate the frames of the %s format by %d degrees: %w",
+			format, degrees, ErrInvalidValue)
+	}
+
+	return nil
+}
+
+// outputSize returns the size of the
+// output frame after the rotation.
+func (video *VideoStream) outputSize() (int, int) {
+	if video.rotation%180 != 0 {
+		return video.frameHeight, video.frameWidth
+	}
+
+	return video.frameWidth, video.frameHeight
+}
+
+// rotateOutput copies the planes of the output
+// frame into the buffer stored one after another
+// without padding applying the rotation.
+func (video *VideoStream) rotateOutput(data []byte) error {
+	desc := C.av_pix_fmt_desc_get(C.enum_AVPixelFormat(video.format))
+
+	if desc == nil {
+		return fmt.Errorf(
+			"unknown pixel format %d: %w",
+			int(video.format), ErrInvalidValue)
+	}
+
+	width, height := video.outputSize()
+	strides, heights, err := video.format.planeLayout(width, height)
+
+	if err != nil {
+		return err
+	}
+
+	steps := planeSteps(desc)
+	offset := 0
+
+	for i := range strides {
+		size := strides[i] * heights[i]
+
+		if i >= len(steps) || steps[i] <= 0 ||
+			video.dstFrame.data[i] == nil {
+			offset += size
+			continue
+		}
+
+		planeWidth, planeHeight := video.frameWidth, video.frameHeight
+
+		if i == 1 || i == 2 {
+			planeWidth = -((-planeWidth) >> desc.log2_chroma_w)
+			planeHeight = -((-planeHeight) >> desc.log2_chroma_h)
+		}
+
+		srcStride := int(video.dstFrame.linesize[i])
+		src := unsafe.Slice((*byte)(unsafe.Pointer(video.dstFrame.data[i])),
+			srcStride*(planeHeight-1)+planeWidth*steps[i])
+
+		transformPlane(data[offset:offset+size], strides[i],
+			src, srcStride, planeWidth, planeHeight, steps[i],
+			video.rotation, video.flip)
+		offset += size
+	}
+
+	return nil
+}
+
+// transformPlane copies the plane of the specified
+// size in elements of the step bytes mirroring it
+// horizontally if needed and rotating it clockwise
+// by the angle in degrees.
+func transformPlane(dst []byte, dstStride int, src []byte, srcStride, width, height, step, degrees int, flip bool) {
+	if degrees == 0 && !flip {
+		for y := 0; y < height; y++ {
+			copy(dst[y*dstStride:y*dstStride+width*step],
+				src[y*srcStride:y*srcStride+width*step])
+		}
+
+		return
+	}
+
+	for y := 0; y < height; y++ {
+		row := src[y*srcStride:]
+
+		for x := 0; x < width; x++ {
+			mx := x
+
+			if flip {
+				mx = width - 1 - x
+			}
+
+			var dx, dy int
+
+			switch degrees {
+			case 90:
+				dx, dy = height-1-y, mx
+
+			case 180:
+				dx, dy = width-1-mx, height-1-y
+
+			case 270:
+				dx, dy = y, width-1-mx
+
+			default:
+				dx, dy = mx, y
+			}
+
+			offset := dy*dstStride + dx*step
+			copy(dst[offset:offset+step], row[x*step:x*step+step])
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package reisen
+
+// #cgo pkg-config: libavutil libswscale
+// #include <libavutil/pixdesc.h>
+// #include <libavutil/imgutils.h>
+// #include <libavutil/mem.h>
+// #include <libswscale/swscale.h>
+import "C"
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"unsafe"
+)
+
+// ScaleMode defines how the decoded
+// picture is fitted into the output
+// frame of the requested size.
+type ScaleMode int
+
+const (
+	// ScaleStretch stretches the picture to
+	// the output frame size ignoring its
+	// aspect ratio.
+	ScaleStretch ScaleMode = iota
+	// ScaleFit scales the picture to fit into
+	// the output frame preserving its aspect
+	// ratio. The rest of the frame is filled
+	// with the padding color (letterboxing).
+	ScaleFit
+	// ScaleFill scales the picture to cover the
+	// whole output frame preserving its aspect
+	// ratio. The parts of the picture outside
+	// the frame are cropped.
+	ScaleFill
+)
+
+// ScaleOptions defines how the decoded
+// video frames are cropped and scaled.
+type ScaleOptions struct {
+	// Width is the width of the output frame.
+	// If it's not positive, it's computed
+	// from the height and the aspect ratio.
+	Width int
+	// Height is the height of the output frame.
+	// If it's not positive, it's computed
+	// from the width and the aspect ratio.
+	//
+	// If neither the width nor the height is
+	// positive, the display size of the
+	// picture is used.
+	Height int
+	// Mode defines how the picture is
+	// fitted into the output frame.
+	Mode ScaleMode
+	// Algorithm is the interpolation algorithm
+	// used for scaling. Bicubic is used
+	// if it's not specified.
+	Algorithm InterpolationAlgorithm
+	// Padding is the color of the borders
+	// added in the fit mode. Black is used
+	// if it's not specified.
+	Padding color.Color
+	// Crop is the rectangle of the decoded
+	// picture in pixels to be shown. The
+	// whole picture is shown if it's empty.
+	Crop image.Rectangle
+	// IgnoreSAR makes the sample aspect ratio
+	// ignored, so the pixels are considered
+	// square.
+	IgnoreSAR bool
+	// AutoRotate makes the frames rotated and
+	// mirrored according to the display matrix
+	// of the stream, so they are upright. The
+	// width and the height of the output frame
+	// are then the ones of the rotated frame,
+	// and the crop rectangle is in the
+	// coordinates of the decoded picture.
+	AutoRotate bool
+}
+
+// scaleGeometry describes the
+// conversion of the picture.
+type scaleGeometry struct {
+	srcRect image.Rectangle
+	width   int
+	height  int
+	dstRect image.Rectangle
+}
+
+// geometry computes how the picture of the
+// specified size and sample aspect ratio
+// is cropped, scaled and placed.
+func (opts ScaleOptions) geometry(srcWidth, srcHeight int, sar C.AVRational, srcFormat, dstFormat PixelFormat) scaleGeometry {
+	src := image.Rect(0, 0, srcWidth, srcHeight)
+
+	if crop := opts.Crop.Intersect(src); !crop.Empty() {
+		src = crop
+	}
+
+	pixelAspect := 1.0
+
+	if !opts.IgnoreSAR && sar.num > 0 && sar.den > 0 {
+		pixelAspect = float64(sar.num) / float64(sar.den)
+	}
+
+	dispWidth := float64(src.Dx()) * pixelAspect
+	dispHeight := float64(src.Dy())
+	width, height := opts.Width, opts.Height
+
+	switch {
+	case width <= 0 && height <= 0:
+		width = roundSize(dispWidth)
+		height = roundSize(dispHeight)
+
+	case width <= 0:
+		width = roundSize(float64(height) * dispWidth / dispHeight)
+
+	case height <= 0:
+		height = roundSize(float64(width) * dispHeight / dispWidth)
+	}
+
+	dst := image.Rect(0, 0, width, height)
+
+	switch opts.Mode {
+	case ScaleFit:
+		scale := math.Min(float64(width)/dispWidth,
+			float64(height)/dispHeight)
+		dstWidth := roundSize(dispWidth * scale)
+		dstHeight := roundSize(dispHeight * scale)
+		x, y := alignDown((width-dstWidth)/2, (height-dstHeight)/2, dstFormat)
+		dst = image.Rect(x, y, x+dstWidth, y+dstHeight)
+
+	case ScaleFill:
+		scale := math.Max(float64(width)/dispWidth,
+			float64(height)/dispHeight)
+		visWidth := roundSize(float64(width) / scale / pixelAspect)
+		visHeight := roundSize(float64(height) / scale)
+
+		if visWidth > src.Dx() {
+			visWidth = src.Dx()
+		}
+
+		if visHeight > src.Dy() {
+			visHeight = src.Dy()
+		}
+
+		x := src.Min.X + (src.Dx()-visWidth)/2
+		y := src.Min.Y + (src.Dy()-visHeight)/2
+		src = image.Rect(x, y, x+visWidth, y+visHeight)
+	}
+
+	// The chroma planes can't be
+	// cropped by half a sample.
+	x, y := alignDown(src.Min.X, src.Min.Y, srcFormat)
+	src = image.Rect(x, y, src.Max.X, src.Max.Y)
+
+	return scaleGeometry{
+		srcRect: src,
+		width:   width,
+		height:  height,
+		dstRect: dst,
+	}
+}
+
+// algorithm returns the interpolation
+// algorithm used for scaling.
+func (opts ScaleOptions) algorithm() InterpolationAlgorithm {
+	if opts.Algorithm == 0 {
+		return InterpolationBicubic
+	}
+
+	return opts.Algorithm
+}
+
+// padding returns the non-premultiplied
+// padding color.
+func (opts ScaleOptions) padding() color.NRGBA {
+	if opts.Padding == nil {
+		return color.NRGBA{A: 0xff}
+	}
+
+	return color.NRGBAModel.Convert(opts.Padding).(color.NRGBA)
+}
+
+// planeOffsets returns the pointers to the pixel
+// at the specified position in every plane
+// of the image of the format.
+func planeOffsets(data *[8]*C.uint8_t, linesize *[8]C.int, format PixelFormat, x, y int) [8]*C.uint8_t {
+	// The planes without components (e.g. the
+	// palette of PAL8 and the pseudo-paletted
+	// formats) must be passed as they are, only
+	// the planes of the pixels are shifted.
+	offsets := *data
+
+	if x == 0 && y == 0 {
+		return offsets
+	}
+
+	desc := C.av_pix_fmt_desc_get(C.enum_AVPixelFormat(format))
+
+	if desc == nil {
+		return offsets
+	}
+
+	steps := planeSteps(desc)
+
+	for i, step := range steps {
+		if data[i] == nil || step <= 0 {
+			continue
+		}
+
+		planeX, planeY := x, y
+
+		if i == 1 || i == 2 {
+			planeX >>= desc.log2_chroma_w
+			planeY >>= desc.log2_chroma_h
+		}
+
+		offsets[i] = (*C.uint8_t)(unsafe.Add(unsafe.Pointer(data[i]),
+			planeY*int(linesize[i])+planeX*step))
+	}
+
+	return offsets
+}
+
+// planeSteps returns the distance in bytes
+// between the adjacent pixels of every
+// plane of the pixel format.
+func planeSteps(desc *C.AVPixFmtDescriptor) []int {
+	steps := []int{}
+
+	for i := 0; i < int(desc.nb_components); i++ {
+		comp := desc.comp[i]
+
+		for len(steps) <= int(comp.plane) {
+			steps = append(steps, 0)
+		}
+
+		steps[comp.plane] = int(comp.step)
+	}
+
+	return steps
+}
+
+// fillPadding fills the whole frame of the
+// format with the padding color.
+func fillPadding(frame *C.AVFrame, format PixelFormat, width, height int, padding color.NRGBA) error {
+	desc := C.av_pix_fmt_desc_get(C.enum_AVPixelFormat(format))
+
+	if desc == nil {
+		return fmt.Errorf(
+			"unknown pixel format %d: %w",
+			int(format), ErrInvalidValue)
+	}
+
+	// Convert a 2x2 RGBA image of the padding
+	// color into the format, so the color value
+	// of every plane can be taken from it.
+	srcBuf := (*C.uint8_t)(C.av_malloc(2 * 2 * 4))
+
+	if srcBuf == nil {
+		return fmt.Errorf(
+			"couldn't allocate an AV buffer")
+	}
+
+	defer C.av_free(unsafe.Pointer(srcBuf))
+
+	src := unsafe.Slice((*byte)(unsafe.Pointer(srcBuf)), 2*2*4)
+
+	for i := 0; i < len(src); i += 4 {
+		src[i] = padding.R
+		src[i+1] = padding.G
+		src[i+2] = padding.B
+		src[i+3] = padding.A
+	}
+
+	swsCtx := C.sws_getCachedContext(nil, 2, 2, C.AV_PIX_FMT_RGBA,
+		2, 2, C.enum_AVPixelFormat(format), C.SWS_POINT, nil, nil, nil)
+
+	if swsCtx == nil {
+		return fmt.Errorf(
+			"couldn't create an SWS context")
+	}
+
+	defer C.sws_freeContext(swsCtx)
+
+	var (
+		dstData     [4]*C.uint8_t
+		dstLinesize [4]C.int
+	)
+
+	size := C.av_image_get_buffer_size(
+		C.enum_AVPixelFormat(format), 2, 2, 1)
+
+	if size < 0 {
+		return newAVError(size, "av_image_get_buffer_size")
+	}
+
+	buf := (*C.uint8_t)(C.av_malloc(bufferSize(size)))
+
+	if buf == nil {
+		return fmt.Errorf(
+			"couldn't allocate an AV buffer")
+	}
+
+	defer C.av_free(unsafe.Pointer(buf))
+
+	status := C.av_image_fill_arrays(&dstData[0], &dstLinesize[0],
+		buf, C.enum_AVPixelFormat(format), 2, 2, 1)
+
+	if status < 0 {
+		return newAVError(status, "av_image_fill_arrays")
+	}
+
+	srcData := [4]*C.uint8_t{srcBuf}
+	srcLinesize := [4]C.int{2 * 4}
+
+	C.sws_scale(swsCtx, &srcData[0], &srcLinesize[0], 0, 2,
+		&dstData[0], &dstLinesize[0])
+
+	for i, step := range planeSteps(desc) {
+		if frame.data[i] == nil || step <= 0 {
+			continue
+		}
+
+		planeWidth, planeHeight := width, height
+
+		if i == 1 || i == 2 {
+			planeWidth = -((-width) >> desc.log2_chroma_w)
+			planeHeight = -((-height) >> desc.log2_chroma_h)
+		}
+
+		value := unsafe.Slice((*byte)(unsafe.Pointer(dstData[i])), step)
+
+		for y := 0; y < planeHeight; y++ {
+			row := unsafe.Slice((*byte)(unsafe.Add(
+				unsafe.Pointer(frame.data[i]),
+				y*int(frame.linesize[i]))), planeWidth*step)
+
+			for x := 0; x < planeWidth; x++ {
+				copy(row[x*step:], value)
+			}
+		}
+	}
+
+	return nil
+}
+
+// alignDown aligns the position to
+// the chroma subsampling of the
+// pixel format.
+func alignDown(x, y int, format PixelFormat) (int, int) {
+	desc := C.av_pix_fmt_desc_get(C.enum_AVPixelFormat(format))
+
+	if desc == nil {
+		return x, y
+	}
+
+	return x &^ (1<<desc.log2_chroma_w - 1),
+		y &^ (1<<desc.log2_chroma_h - 1)
+}
+
+// roundSize rounds the size in pixels
+// making it at least 1.
+func roundSize(size float64) int {
+	if size < 1 {
+		return 1
+	}
+
+	return int(math.Round(size))
+}
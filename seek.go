@@ -0,0 +1,168 @@
+package reisen
+
+// #cgo pkg-config: libavformat libavutil
+// #include <libavformat/avformat.h>
+// #include <libavutil/mathematics.h>
+// #include <stdint.h>
+import "C"
+import (
+	"fmt"
+	"time"
+)
+
+// SeekMode defines how precisely
+// the media is positioned on seeking.
+type SeekMode int
+
+const (
+	// SeekKeyframe positions the media at the
+	// closest keyframe preceding the target,
+	// so the first frames read after seeking
+	// can be earlier than the target.
+	SeekKeyframe SeekMode = iota
+	// SeekAccurate positions the media at the
+	// closest keyframe preceding the target and
+	// discards the frames decoded before the
+	// target, so the first frame read after
+	// seeking is the one shown at the target.
+	SeekAccurate
+)
+
+// Seek positions all the streams of
+// the media at the specified time offset
+// since the start of the media.
+//
+// The buffers of all the opened decoders
+// are flushed, so the frames read after
+// seeking don't depend on the packets
+// read before.
+//
+// If an index is set with UseIndex, the
+// keyframe is looked up in it.
+func (media *Media) Seek(t time.Duration, mode SeekMode) error {
+	if !media.seekable() {
+		return fmt.Errorf(
+			"couldn't seek the media: the media source is not seekable")
+	}
+
+	ts := int64(t.Seconds() * float64(TimeBase))
+
+	if media.ctx.start_time != C.AV_NOPTS_VALUE {
+		ts += int64(media.ctx.start_time)
+	}
+
+	found := false
+
+	if media.index != nil {
+		var err error
+		found, err = media.seekIndex(ts)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if !found {
+		status := C.avformat_seek_file(media.ctx, -1,
+			C.INT64_MIN, C.int64_t(ts), C.int64_t(ts), 0)
+
+		if status < 0 {
+			return newAVError(status, "avformat_seek_file")
+		}
+	}
+
+	if mode != SeekAccurate {
+		ts = NoTimestamp
+	}
+
+	media.reset(ts, C.AVRational{
+		num: 1, den: C.AV_TIME_BASE})
+
+	return nil
+}
+
+// SeekByte positions the media at the
+// specified byte offset. It's useful for
+// the formats without timestamps like raw
+// streams.
+//
+// The buffers of all the opened
+// decoders are flushed.
+func (media *Media) SeekByte(offset int64) error {
+	if !media.seekable() {
+		return fmt.Errorf(
+			"couldn't seek the media: the media source is not seekable")
+	}
+
+	status := C.avformat_seek_file(media.ctx, -1,
+		C.INT64_MIN, C.int64_t(offset), C.int64_t(offset),
+		C.AVSEEK_FLAG_BYTE)
+
+	if status < 0 {
+		return newAVError(status, "avformat_seek_file")
+	}
+
+	media.reset(NoTimestamp, C.AVRational{
+		num: 1, den: C.AV_TIME_BASE})
+
+	return nil
+}
+
+// SeekFrame positions the media at the n-th
+// displayed frame (counting from 0) of the
+// video stream. The first frame read from
+// the stream after seeking is exactly the
+// n-th one, and its IndexDisplay is n.
+//
+// The frame number is derived from the
+// average frame rate of the stream, so
+// the stream should have a constant
+// frame rate.
+func (video *VideoStream) SeekFrame(n int64) error {
+	if !video.media.seekable() {
+		return fmt.Errorf(
+			"couldn't seek the stream: the media source is not seekable")
+	}
+
+	if n < 0 {
+		return fmt.Errorf(
+			"couldn't seek to the frame %d: %w",
+			n, ErrInvalidValue)
+	}
+
+	frameRate := video.frameRate()
+
+	if frameRate.num <= 0 || frameRate.den <= 0 {
+		return fmt.Errorf(
+			"couldn't seek to the frame %d: the frame rate is unknown", n)
+	}
+
+	ts := int64(C.av_rescale_q(C.int64_t(n), C.AVRational{
+		num: frameRate.den, den: frameRate.num},
+		video.inner.time_base)) + video.startTime()
+
+	status := C.avformat_seek_file(video.media.ctx,
+		video.inner.index, C.INT64_MIN, C.int64_t(ts),
+		C.int64_t(ts), 0)
+
+	if status < 0 {
+		return newAVError(status, "avformat_seek_file")
+	}
+
+	video.media.reset(ts, video.inner.time_base)
+
+	return nil
+}
+
+// reset discards the last packet read and
+// the state of all the decoders after seeking
+// to the timestamp in the time base units.
+func (media *Media) reset(ts int64, timeBase C.AVRational) {
+	if media.packet != nil {
+		C.av_packet_unref(media.packet)
+	}
+
+	for _, stream := range media.streams {
+		stream.reset(ts, timeBase)
+	}
+}
@@ -0,0 +1,177 @@
+package reisen
+
+// #cgo pkg-config: libavcodec
+// #include <libavcodec/avcodec.h>
+import "C"
+import (
+	"encoding/binary"
+	"fmt"
+	"unsafe"
+)
+
+// SideDataType is a type of
+// the packet side data.
+type SideDataType int
+
+const (
+	// SideDataPalette is an AVPALETTE_SIZE bytes
+	// long palette of 256 ARGB colors.
+	SideDataPalette SideDataType = C.AV_PKT_DATA_PALETTE
+	// SideDataNewExtradata is the new codec
+	// extradata which should replace the
+	// current one.
+	SideDataNewExtradata SideDataType = C.AV_PKT_DATA_NEW_EXTRADATA
+	// SideDataParamChange holds the changed
+	// decoder parameters.
+	SideDataParamChange SideDataType = C.AV_PKT_DATA_PARAM_CHANGE
+	// SideDataReplayGain holds the
+	// ReplayGain information.
+	SideDataReplayGain SideDataType = C.AV_PKT_DATA_REPLAYGAIN
+	// SideDataDisplayMatrix is a 3x3 transformation
+	// matrix describing how the decoded frame
+	// should be displayed.
+	SideDataDisplayMatrix SideDataType = C.AV_PKT_DATA_DISPLAYMATRIX
+	// SideDataStereo3D holds the stereoscopic
+	// 3D information.
+	SideDataStereo3D SideDataType = C.AV_PKT_DATA_STEREO3D
+	// SideDataSkipSamples holds the number of
+	// samples to be skipped at the start and
+	// at the end of the audio frame.
+	SideDataSkipSamples SideDataType = C.AV_PKT_DATA_SKIP_SAMPLES
+	// SideDataStringsMetadata holds the metadata
+	// tags updated by the packet.
+	SideDataStringsMetadata SideDataType = C.AV_PKT_DATA_STRINGS_METADATA
+	// SideDataMetadataUpdate means the
+	// metadata of the stream was updated.
+	SideDataMetadataUpdate SideDataType = C.AV_PKT_DATA_METADATA_UPDATE
+	// SideDataMPEGTSStreamID is the MPEG-TS
+	// stream ID of the packet.
+	SideDataMPEGTSStreamID SideDataType = C.AV_PKT_DATA_MPEGTS_STREAM_ID
+	// SideDataMasteringDisplayMetadata holds
+	// the HDR mastering display metadata.
+	SideDataMasteringDisplayMetadata SideDataType = C.AV_PKT_DATA_MASTERING_DISPLAY_METADATA
+	// SideDataSpherical holds the
+	// spherical video information.
+	SideDataSpherical SideDataType = C.AV_PKT_DATA_SPHERICAL
+	// SideDataContentLightLevel holds
+	// the HDR content light level.
+	SideDataContentLightLevel SideDataType = C.AV_PKT_DATA_CONTENT_LIGHT_LEVEL
+	// SideDataA53CC holds the
+	// ATSC A53 closed captions.
+	SideDataA53CC SideDataType = C.AV_PKT_DATA_A53_CC
+	// SideDataEncryptionInitInfo holds the
+	// encryption initialization data.
+	SideDataEncryptionInitInfo SideDataType = C.AV_PKT_DATA_ENCRYPTION_INIT_INFO
+	// SideDataEncryptionInfo holds the
+	// encryption info of the packet.
+	SideDataEncryptionInfo SideDataType = C.AV_PKT_DATA_ENCRYPTION_INFO
+	// SideDataAFD holds the active
+	// format description.
+	SideDataAFD SideDataType = C.AV_PKT_DATA_AFD
+	// SideDataICCProfile holds
+	// the ICC profile.
+	SideDataICCProfile SideDataType = C.AV_PKT_DATA_ICC_PROFILE
+)
+
+// String returns the name of the side data type.
+func (sideDataType SideDataType) String() string {
+	name := C.av_packet_side_data_name(
+		C.enum_AVPacketSideDataType(sideDataType))
+
+	if name == nil {
+		return ""
+	}
+
+	return C.GoString(name)
+}
+
+// SideData is a piece of metadata
+// attached to the packet.
+type SideData struct {
+	// Type is the type of the side data.
+	Type SideDataType
+	// Data is the raw side data.
+	Data []byte
+}
+
+// SkipSamples returns the number of samples
+// to be skipped at the start and at the end
+// of the audio frame.
+func (sideData SideData) SkipSamples() (uint32, uint32, error) {
+	if sideData.Type != SideDataSkipSamples || len(sideData.Data) < 8 {
+		return 0, 0, fmt.Errorf(
+			"the side data doesn't contain skip samples")
+	}
+
+	return binary.LittleEndian.Uint32(sideData.Data[0:4]),
+		binary.LittleEndian.Uint32(sideData.Data[4:8]), nil
+}
+
+// MPEGTSStreamID returns the MPEG-TS
+// stream ID of the packet.
+func (sideData SideData) MPEGTSStreamID() (uint8, error) {
+	if sideData.Type != SideDataMPEGTSStreamID || len(sideData.Data) < 1 {
+		return 0, fmt.Errorf(
+			"the side data doesn't contain an MPEG-TS stream ID")
+	}
+
+	return sideData.Data[0], nil
+}
+
+// DisplayMatrix returns the 3x3 display
+// transformation matrix in the row-major
+// order with 16.16 fixed-point values
+// except for the last column which is 2.30.
+func (sideData SideData) DisplayMatrix() ([9]int32, error) {
+	var matrix [9]int32
+
+	if sideData.Type != SideDataDisplayMatrix ||
+		len(sideData.Data) < len(matrix)*4 {
+		return matrix, fmt.Errorf(
+			"the side data doesn't contain a display matrix")
+	}
+
+	// The matrix is stored in
+	// the native byte order.
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(
+		&matrix[0])), len(matrix)*4), sideData.Data)
+
+	return matrix, nil
+}
+
+// Palette returns the 256
+// ARGB colors of the palette.
+func (sideData SideData) Palette() ([]uint32, error) {
+	if sideData.Type != SideDataPalette || len(sideData.Data) < 256*4 {
+		return nil, fmt.Errorf(
+			"the side data doesn't contain a palette")
+	}
+
+	// The colors are stored in
+	// the native byte order.
+	palette := make([]uint32, 256)
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(
+		&palette[0])), len(palette)*4), sideData.Data)
+
+	return palette, nil
+}
+
+// newSideData copies the side
+// data of the libAV packet.
+func newSideData(cPkt *C.AVPacket) []SideData {
+	if cPkt.side_data == nil || cPkt.side_data_elems <= 0 {
+		return nil
+	}
+
+	entries := unsafe.Slice(cPkt.side_data, cPkt.side_data_elems)
+	sideData := make([]SideData, 0, len(entries))
+
+	for _, entry := range entries {
+		sideData = append(sideData, SideData{
+			Type: SideDataType(entry._type),
+			Data: C.GoBytes(unsafe.Pointer(entry.data), entry.size),
+		})
+	}
+
+	return sideData
+}
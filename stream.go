@@ -4,10 +4,12 @@ package reisen
 // #include <libavcodec/avcodec.h>
 // #include <libavformat/avformat.h>
 // #include <libavutil/avconfig.h>
+// #include <libavutil/mathematics.h>
 // #include <libavcodec/bsf.h>
 import "C"
 import (
 	"fmt"
+	"sync"
 	"time"
 	"unsafe"
 )
@@ -58,9 +60,18 @@ type Stream interface {
 
 	// open opens the stream for decoding.
 	open() error
+	// feed passes the packet read from
+	// the media to the stream decoder.
+	feed(*C.AVPacket) error
 	// read decodes the packet and obtains a
 	// frame from it.
 	read() (bool, error)
+	// restart makes the drained decoder
+	// accept new packets again.
+	restart()
+	// reset discards the decoder state
+	// after seeking to the timestamp.
+	reset(int64, C.AVRational)
 	// close closes the stream for decoding.
 	close() error
 
@@ -96,8 +107,21 @@ type Stream interface {
 	// FrameCount returns the total number
 	// of frames in the stream.
 	FrameCount() int64
+	// Metadata returns the metadata
+	// tags of the stream.
+	Metadata() map[string]string
+	// Language returns the language
+	// of the stream or "" if it's
+	// not specified.
+	Language() string
+	// Disposition returns the flags
+	// describing the purpose of the stream.
+	Disposition() Disposition
 	// Open opens the stream for decoding.
 	Open() error
+	// Opened returns 'true' if the stream
+	// is opened for decoding.
+	Opened() bool
 	// Rewind rewinds the whole media to the
 	// specified time location based on the stream.
 	Rewind(time.Duration) error
@@ -112,7 +136,16 @@ type Stream interface {
 	// filter from the stream and frees its memory.
 	RemoveFilter() error
 	// ReadFrame decodes the next frame from the stream.
+	//
+	// A single packet can contain several frames,
+	// so ReadFrame should be called until it
+	// returns no frame before reading the next
+	// packet.
 	ReadFrame() (Frame, bool, error)
+	// Flush signals the end of the stream to the
+	// decoder, so all the frames buffered inside
+	// it can be obtained with ReadFrame.
+	Flush() error
 	// Closes the stream for decoding.
 	Close() error
 }
@@ -130,8 +163,29 @@ type baseStream struct {
 	filterCtx       *C.AVBSFContext
 	filterInPacket  *C.AVPacket
 	filterOutPacket *C.AVPacket
+	packet          *C.AVPacket
+	pending         bool
+	held            bool
 	skip            bool
+	draining        bool
+	drainSent       bool
 	opened          bool
+	seeking         bool
+	seekTarget      int64
+	pool            *sync.Pool
+	info            streamInfo
+}
+
+// streamInfo holds the properties of the
+// libAV stream kept by the stream detached
+// from the media.
+type streamInfo struct {
+	index         int
+	timeBase      C.AVRational
+	realFrameRate C.AVRational
+	avgFrameRate  C.AVRational
+	startTime     int64
+	frameCount    int64
 }
 
 // Opened returns 'true' if the stream
@@ -142,7 +196,7 @@ func (stream *baseStream) Opened() bool {
 
 // Index returns the index of the stream.
 func (stream *baseStream) Index() int {
-	return int(stream.inner.index)
+	return stream.properties().index
 }
 
 // Type returns the stream media data type.
@@ -177,6 +231,10 @@ func (stream *baseStream) BitRate() int64 {
 
 // Duration returns the duration of the stream.
 func (stream *baseStream) Duration() (time.Duration, error) {
+	if stream.inner == nil {
+		return 0, nil
+	}
+
 	dur := stream.inner.duration
 
 	if dur < 0 {
@@ -197,21 +255,70 @@ func (stream *baseStream) Duration() (time.Duration, error) {
 // multiplied by this factor to get duration
 // in seconds.
 func (stream *baseStream) TimeBase() (int, int) {
-	return int(stream.inner.time_base.num),
-		int(stream.inner.time_base.den)
+	timeBase := stream.properties().timeBase
+
+	return int(timeBase.num), int(timeBase.den)
 }
 
 // FrameRate returns the frame rate of the stream
 // as a fraction with a numerator and a denominator.
 func (stream *baseStream) FrameRate() (int, int) {
-	return int(stream.inner.r_frame_rate.num),
-		int(stream.inner.r_frame_rate.den)
+	frameRate := stream.properties().realFrameRate
+
+	return int(frameRate.num), int(frameRate.den)
 }
 
 // FrameCount returns the total number of frames
 // in the stream.
 func (stream *baseStream) FrameCount() int64 {
-	return int64(stream.inner.nb_frames)
+	return stream.properties().frameCount
+}
+
+// Metadata returns the metadata tags of the
+// stream (e.g. language, title or handler_name).
+func (stream *baseStream) Metadata() map[string]string {
+	if stream.inner == nil {
+		return map[string]string{}
+	}
+
+	return dictionaryMap(stream.inner.metadata)
+}
+
+// Language returns the ISO 639-2 language
+// code of the stream or "" if it's not
+// specified.
+func (stream *baseStream) Language() string {
+	if stream.inner == nil {
+		return ""
+	}
+
+	return dictionaryValue(stream.inner.metadata, "language")
+}
+
+// UseFramePool makes the stream reuse the
+// frames returned with Release instead of
+// allocating new ones, so steady-state
+// decoding allocates no memory.
+func (stream *baseStream) UseFramePool(enabled bool) {
+	if !enabled {
+		stream.pool = nil
+		return
+	}
+
+	if stream.pool == nil {
+		stream.pool = &sync.Pool{}
+	}
+}
+
+// Disposition returns the flags describing
+// the purpose of the stream (e.g. default,
+// forced or attached picture).
+func (stream *baseStream) Disposition() Disposition {
+	if stream.inner == nil {
+		return 0
+	}
+
+	return Disposition(stream.inner.disposition)
 }
 
 // ApplyFilter applies a filter defined
@@ -221,32 +328,28 @@ func (stream *baseStream) ApplyFilter(args string) error {
 		C.CString(args), &stream.filterCtx)
 
 	if status < 0 {
-		return fmt.Errorf(
-			"%d: couldn't create a filter context", status)
+		return newAVError(status, "av_bsf_list_parse_str")
 	}
 
 	status = C.avcodec_parameters_copy(stream.filterCtx.par_in, stream.codecParams)
 
 	if status < 0 {
-		return fmt.Errorf(
-			"%d: couldn't copy the input codec parameters to the filter", status)
+		return newAVError(status, "avcodec_parameters_copy")
 	}
 
 	status = C.avcodec_parameters_copy(stream.filterCtx.par_out, stream.codecParams)
 
 	if status < 0 {
-		return fmt.Errorf(
-			"%d: couldn't copy the output codec parameters to the filter", status)
+		return newAVError(status, "avcodec_parameters_copy")
 	}
 
-	stream.filterCtx.time_base_in = stream.inner.time_base
-	stream.filterCtx.time_base_out = stream.inner.time_base
+	stream.filterCtx.time_base_in = stream.properties().timeBase
+	stream.filterCtx.time_base_out = stream.properties().timeBase
 
 	status = C.av_bsf_init(stream.filterCtx)
 
 	if status < 0 {
-		return fmt.Errorf(
-			"%d: couldn't initialize the filter context", status)
+		return newAVError(status, "av_bsf_init")
 	}
 
 	stream.filterInPacket = C.av_packet_alloc()
@@ -303,6 +406,11 @@ func (stream *baseStream) RemoveFilter() error {
 // the streams of the playback to
 // desynchronyze.
 func (stream *baseStream) Rewind(t time.Duration) error {
+	if !stream.media.seekable() {
+		return fmt.Errorf(
+			"couldn't rewind the stream: the media source is not seekable")
+	}
+
 	tmNum, tmDen := stream.TimeBase()
 	factor := float64(tmDen) / float64(tmNum)
 	seconds := t.Seconds()
@@ -313,10 +421,27 @@ func (stream *baseStream) Rewind(t time.Duration) error {
 		C.AVSEEK_FLAG_FRAME|C.AVSEEK_FLAG_BACKWARD)
 
 	if status < 0 {
-		return fmt.Errorf(
-			"%d: couldn't rewind the stream", status)
+		return newAVError(status, "av_seek_frame")
 	}
 
+	stream.media.reset(NoTimestamp, stream.inner.time_base)
+
+	return nil
+}
+
+// Flush signals the end of the stream to the
+// decoder, so all the frames buffered inside
+// it can be obtained with ReadFrame.
+//
+// Once ReadFrame returns 'false', the
+// decoder is completely drained.
+func (stream *baseStream) Flush() error {
+	if !stream.opened {
+		return fmt.Errorf("the stream is not opened for decoding")
+	}
+
+	stream.draining = true
+
 	return nil
 }
 
@@ -355,15 +480,13 @@ func (stream *baseStream) open() error {
 		stream.codecCtx, stream.codecParams)
 
 	if status < 0 {
-		return fmt.Errorf(
-			"%d: couldn't send codec parameters to the context", status)
+		return newAVError(status, "avcodec_parameters_to_context")
 	}
 
 	status = C.avcodec_open2(stream.codecCtx, stream.codec, nil)
 
 	if status < 0 {
-		return fmt.Errorf(
-			"%d: couldn't open the codec context", status)
+		return newAVError(status, "avcodec_open2")
 	}
 
 	stream.frame = C.av_frame_alloc()
@@ -373,70 +496,273 @@ func (stream *baseStream) open() error {
 			"couldn't allocate a new frame")
 	}
 
+	stream.packet = C.av_packet_alloc()
+
+	if stream.packet == nil {
+		return fmt.Errorf(
+			"couldn't allocate a new packet")
+	}
+
+	// The stream can be opened right after
+	// its packet was read from the media.
+	if lastPacket := stream.lastPacket(); lastPacket != nil {
+		err := stream.feed(lastPacket)
+
+		if err != nil {
+			return err
+		}
+	}
+
 	stream.opened = true
 
 	return nil
 }
 
-// read decodes the packet and obtains a
-// frame from it.
-func (stream *baseStream) read() (bool, error) {
-	readPacket := stream.media.packet
+// properties returns the properties of the
+// libAV stream or the ones kept by the stream
+// if it's detached from the media.
+func (stream *baseStream) properties() streamInfo {
+	if stream.inner == nil {
+		return stream.info
+	}
 
-	if stream.filterCtx != nil {
-		readPacket = stream.filterOutPacket
+	return streamInfo{
+		index:         int(stream.inner.index),
+		timeBase:      stream.inner.time_base,
+		realFrameRate: stream.inner.r_frame_rate,
+		avgFrameRate:  stream.inner.avg_frame_rate,
+		startTime:     int64(stream.inner.start_time),
+		frameCount:    int64(stream.inner.nb_frames),
 	}
+}
 
-	status := C.avcodec_send_packet(
-		stream.codecCtx, readPacket)
+// detached returns the copy of the stream
+// not bound to the media, so the stream can
+// be decoded independently and outlive it.
+//
+// The codec parameters are copied into the
+// specified ones owned by the caller.
+func (stream *baseStream) detached(params *C.AVCodecParameters) (baseStream, error) {
+	status := C.avcodec_parameters_copy(params, stream.codecParams)
 
 	if status < 0 {
-		stream.skip = false
+		return baseStream{}, newAVError(status, "avcodec_parameters_copy")
+	}
+
+	return baseStream{
+		codecParams: params,
+		codec:       stream.codec,
+		info:        stream.properties(),
+	}, nil
+}
 
-		return false, fmt.Errorf(
-			"%d: couldn't send the packet to the codec context", status)
+// lastPacket returns the packet of the
+// stream most recently read from the
+// media or nil if there's no such packet.
+func (stream *baseStream) lastPacket() *C.AVPacket {
+	if stream.media == nil || stream.media.packet == nil {
+		return nil
 	}
 
-	status = C.avcodec_receive_frame(
-		stream.codecCtx, stream.frame)
+	packet := stream.media.packet
+
+	if packet.data == nil ||
+		packet.stream_index != stream.inner.index {
+		return nil
+	}
+
+	if stream.filterCtx != nil {
+		return stream.filterOutPacket
+	}
+
+	return packet
+}
+
+// feed passes the packet read from the
+// media to the stream decoder.
+//
+// The packet is sent to the decoder on the
+// next read, so the previous packet is
+// dropped if it hasn't been sent yet.
+func (stream *baseStream) feed(packet *C.AVPacket) error {
+	if stream.pending {
+		C.av_packet_unref(stream.packet)
+		stream.pending = false
+	}
+
+	status := C.av_packet_ref(stream.packet, packet)
 
 	if status < 0 {
-		if status == C.int(ErrorAgain) {
-			stream.skip = true
+		return newAVError(status, "av_packet_ref")
+	}
+
+	stream.pending = true
+
+	return nil
+}
+
+// send passes the pending packet to the
+// decoder or signals the end of the stream
+// if the stream is being drained.
+func (stream *baseStream) send() error {
+	if stream.pending {
+		status := C.avcodec_send_packet(
+			stream.codecCtx, stream.packet)
+
+		// The decoder has frames to be received
+		// first, so the packet is kept pending.
+		if status == C.int(ErrAgain.Code) {
+			return nil
+		}
+
+		C.av_packet_unref(stream.packet)
+		stream.pending = false
+
+		if status < 0 {
+			return newAVError(status, "avcodec_send_packet")
+		}
+	}
+
+	if stream.draining && !stream.pending && !stream.drainSent {
+		status := C.avcodec_send_packet(stream.codecCtx, nil)
+
+		if status == C.int(ErrAgain.Code) {
+			return nil
+		}
+
+		if status < 0 && status != C.AVERROR_EOF {
+			return newAVError(status, "avcodec_send_packet")
+		}
+
+		stream.drainSent = true
+	}
+
+	return nil
+}
+
+// read decodes the packet and obtains a
+// frame from it.
+//
+// If the decoder needs more packets to
+// produce a frame, the skip flag is set.
+// If the decoder is completely drained,
+// 'false' is returned.
+func (stream *baseStream) read() (bool, error) {
+	stream.skip = false
+	err := stream.send()
+
+	if err != nil {
+		return false, err
+	}
+
+	for {
+		status := C.avcodec_receive_frame(
+			stream.codecCtx, stream.frame)
+
+		if status < 0 {
+			if status == C.int(ErrAgain.Code) {
+				stream.skip = true
+				return true, nil
+			}
+
+			if status == C.AVERROR_EOF {
+				return false, nil
+			}
+
+			return false, newAVError(status, "avcodec_receive_frame")
+		}
+
+		// After the accurate seek the frames
+		// preceding the target are discarded.
+		if !stream.beforeTarget() {
 			return true, nil
 		}
+	}
+}
 
-		stream.skip = false
+// beforeTarget returns 'true' if the decoded
+// frame ends before the target of the
+// accurate seek.
+func (stream *baseStream) beforeTarget() bool {
+	if !stream.seeking {
+		return false
+	}
+
+	pts := int64(stream.frame.best_effort_timestamp)
 
-		return false, fmt.Errorf(
-			"%d: couldn't receive the frame from the codec context", status)
+	if pts == NoTimestamp {
+		return false
 	}
 
-	C.av_packet_unref(stream.media.packet)
+	end := pts + int64(stream.frame.pkt_duration)
 
-	if stream.filterInPacket != nil {
-		C.av_packet_unref(stream.filterInPacket)
+	if pts < stream.seekTarget && (end <= stream.seekTarget ||
+		stream.frame.pkt_duration <= 0) {
+		return true
 	}
 
-	if stream.filterOutPacket != nil {
-		C.av_packet_unref(stream.filterOutPacket)
+	stream.seeking = false
+
+	return false
+}
+
+// restart makes the drained decoder
+// accept new packets again.
+func (stream *baseStream) restart() {
+	if !stream.opened || !stream.draining {
+		return
 	}
 
-	stream.skip = false
+	C.avcodec_flush_buffers(stream.codecCtx)
+	stream.draining = false
+	stream.drainSent = false
+}
+
+// reset discards the packets and frames
+// kept by the decoder after seeking. If the
+// timestamp (in the specified time base
+// units) is not NoTimestamp, the frames
+// preceding it are discarded on reading.
+func (stream *baseStream) reset(ts int64, timeBase C.AVRational) {
+	if stream.filterCtx != nil {
+		C.av_bsf_flush(stream.filterCtx)
+	}
 
-	return true, nil
+	stream.seeking = false
+	stream.held = false
+
+	if !stream.opened {
+		return
+	}
+
+	if stream.pending {
+		C.av_packet_unref(stream.packet)
+		stream.pending = false
+	}
+
+	C.avcodec_flush_buffers(stream.codecCtx)
+	stream.draining = false
+	stream.drainSent = false
+
+	if ts != NoTimestamp {
+		stream.seeking = true
+		stream.seekTarget = int64(C.av_rescale_q(C.int64_t(ts),
+			timeBase, stream.inner.time_base))
+	}
 }
 
 // close closes the stream for decoding.
 func (stream *baseStream) close() error {
 	C.av_free(unsafe.Pointer(stream.frame))
 	stream.frame = nil
+	C.av_packet_free(&stream.packet)
+	stream.pending = false
+	stream.held = false
 
 	status := C.avcodec_close(stream.codecCtx)
 
 	if status < 0 {
-		return fmt.Errorf(
-			"%d: couldn't close the codec", status)
+		return newAVError(status, "avcodec_close")
 	}
 
 	if stream.filterCtx != nil {
@@ -454,6 +780,9 @@ func (stream *baseStream) close() error {
 		stream.filterOutPacket = nil
 	}
 
+	stream.draining = false
+	stream.drainSent = false
+	stream.seeking = false
 	stream.opened = false
 
 	return nil
@@ -8,4 +8,7 @@ const (
 	// TimeBase is a global time base
 	// used for describing media containers.
 	TimeBase int = C.AV_TIME_BASE
+	// NoTimestamp denotes an
+	// unknown timestamp value.
+	NoTimestamp int64 = C.AV_NOPTS_VALUE
 )
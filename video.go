@@ -5,11 +5,14 @@ package reisen
 // #include <libavformat/avformat.h>
 // #include <libavutil/avutil.h>
 // #include <libavutil/imgutils.h>
+// #include <libavutil/mathematics.h>
 // #include <libswscale/swscale.h>
 // #include <inttypes.h>
 import "C"
 import (
 	"fmt"
+	"image"
+	"io"
 	"unsafe"
 )
 
@@ -17,9 +20,16 @@ import (
 // video frames.
 type VideoStream struct {
 	baseStream
-	swsCtx    *C.struct_SwsContext
-	rgbaFrame *C.AVFrame
-	bufSize   C.int
+	swsCtx      *C.struct_SwsContext
+	dstFrame    *C.AVFrame
+	bufSize     C.int
+	frameWidth  int
+	frameHeight int
+	options     ScaleOptions
+	geometry    scaleGeometry
+	format      PixelFormat
+	rotation    int
+	flip        bool
 }
 
 // AspectRatio returns the fraction of the video
@@ -30,17 +40,49 @@ func (video *VideoStream) AspectRatio() (int, int) {
 }
 
 // Width returns the width of the video
-// stream frame.
+// stream frame. If the frames are rotated
+// by 90 or 270 degrees while decoding,
+// it's the height of the decoded frame.
 func (video *VideoStream) Width() int {
+	if video.rotation%180 != 0 {
+		return int(video.codecParams.height)
+	}
+
 	return int(video.codecParams.width)
 }
 
 // Height returns the height of the video
-// stream frame.
+// stream frame. If the frames are rotated
+// by 90 or 270 degrees while decoding,
+// it's the width of the decoded frame.
 func (video *VideoStream) Height() int {
+	if video.rotation%180 != 0 {
+		return int(video.codecParams.width)
+	}
+
 	return int(video.codecParams.height)
 }
 
+// DisplaySize returns the size the video
+// stream frame should be displayed with
+// according to its sample aspect ratio.
+func (video *VideoStream) DisplaySize() (int, int) {
+	width := int(video.codecParams.width)
+	height := int(video.codecParams.height)
+	sarNum, sarDen := video.AspectRatio()
+
+	if sarNum > 0 && sarDen > 0 {
+		width = roundSize(float64(width) *
+			float64(sarNum) / float64(sarDen))
+	}
+
+	if video.rotation%180 != 0 {
+		return height, width
+	}
+
+	return width, height
+}
+
 // OpenDecode opens the video stream for
 // decoding with default parameters.
 func (video *VideoStream) Open() error {
@@ -52,49 +94,168 @@ func (video *VideoStream) Open() error {
 
 // OpenDecode opens the video stream for
 // decoding with the specified parameters.
+//
+// If the width or the height is not positive,
+// the frames are not resized.
 func (video *VideoStream) OpenDecode(width, height int, alg InterpolationAlgorithm) error {
+	return video.OpenDecodeFormat(width, height,
+		alg, PixelFormatRGBA)
+}
+
+// OpenDecodeFormat opens the video stream for
+// decoding into the specified pixel format.
+//
+// If the format is PixelFormatNative, the
+// frames are neither converted nor resized.
+func (video *VideoStream) OpenDecodeFormat(width, height int, alg InterpolationAlgorithm, format PixelFormat) error {
+	// The frames are not resized
+	// if either size is unknown.
+	if width <= 0 || height <= 0 {
+		width, height = 0, 0
+	}
+
+	return video.OpenDecodeScaled(ScaleOptions{
+		Width:     width,
+		Height:    height,
+		Mode:      ScaleStretch,
+		Algorithm: alg,
+		IgnoreSAR: true,
+	}, format)
+}
+
+// OpenDecodeScaled opens the video stream for
+// decoding into the specified pixel format
+// cropping and scaling the frames according
+// to the options.
+//
+// If the format is PixelFormatNative, the
+// options are ignored.
+func (video *VideoStream) OpenDecodeScaled(opts ScaleOptions, format PixelFormat) error {
 	err := video.open()
 
 	if err != nil {
 		return err
 	}
 
-	video.rgbaFrame = C.av_frame_alloc()
+	video.dstFrame = C.av_frame_alloc()
 
-	if video.rgbaFrame == nil {
+	if video.dstFrame == nil {
 		return fmt.Errorf(
-			"couldn't allocate a new RGBA frame")
+			"couldn't allocate a new output frame")
 	}
 
-	video.bufSize = C.av_image_get_buffer_size(
-		C.AV_PIX_FMT_RGBA, C.int(width), C.int(height), 1)
+	video.options = opts
+	video.format = format
 
-	if video.bufSize < 0 {
-		return fmt.Errorf(
-			"%d: couldn't get the buffer size", video.bufSize)
+	// The frame parameters may be unknown
+	// until the first frame is decoded.
+	if format == PixelFormatNative {
+		return nil
 	}
 
-	buf := (*C.uint8_t)(unsafe.Pointer(
-		C.av_malloc(bufferSize(video.bufSize))))
+	if opts.AutoRotate {
+		rotation, flip := video.Rotation()
+		err = checkRotation(format, rotation)
 
-	if buf == nil {
-		return fmt.Errorf(
-			"couldn't allocate an AV buffer")
-	}
+		if err != nil {
+			return err
+		}
 
-	status := C.av_image_fill_arrays(&video.rgbaFrame.data[0],
-		&video.rgbaFrame.linesize[0], buf, C.AV_PIX_FMT_RGBA,
-		C.int(width), C.int(height), 1)
+		video.rotation = rotation
+		video.flip = flip
+	}
 
-	if status < 0 {
-		return fmt.Errorf(
-			"%d: couldn't fill the image arrays", status)
+	if video.codecCtx.width <= 0 || video.codecCtx.height <= 0 ||
+		video.codecCtx.pix_fmt == C.AV_PIX_FMT_NONE {
+		return nil
 	}
 
-	video.swsCtx = C.sws_getContext(video.codecCtx.width,
+	return video.initScaler(video.codecCtx.width,
 		video.codecCtx.height, video.codecCtx.pix_fmt,
-		C.int(width), C.int(height),
-		C.AV_PIX_FMT_RGBA, C.int(alg), nil, nil, nil)
+		video.codecCtx.sample_aspect_ratio)
+}
+
+// OpenDecodeNative opens the video stream
+// for decoding without converting and
+// resizing the frames, so their planes
+// are copied from the decoder only once.
+func (video *VideoStream) OpenDecodeNative() error {
+	return video.OpenDecodeFormat(0, 0,
+		InterpolationBicubic, PixelFormatNative)
+}
+
+// initScaler prepares the SWS context and the
+// output frame buffer for converting the decoded
+// frames of the specified size and format.
+func (video *VideoStream) initScaler(srcWidth, srcHeight C.int, srcFormat C.enum_AVPixelFormat, sar C.AVRational) error {
+	opts := video.options
+
+	// The requested size is the size
+	// of the rotated frame.
+	if video.rotation%180 != 0 {
+		opts.Width, opts.Height = opts.Height, opts.Width
+	}
+
+	geometry := opts.geometry(int(srcWidth),
+		int(srcHeight), sar, PixelFormat(srcFormat), video.format)
+	width, height := geometry.width, geometry.height
+
+	if width != video.frameWidth || height != video.frameHeight {
+		C.av_freep(unsafe.Pointer(&video.dstFrame.data[0]))
+		video.frameWidth = 0
+		video.frameHeight = 0
+		video.geometry = scaleGeometry{}
+
+		video.bufSize = C.av_image_get_buffer_size(
+			C.enum_AVPixelFormat(video.format),
+			C.int(width), C.int(height), 1)
+
+		if video.bufSize < 0 {
+			return newAVError(video.bufSize, "av_image_get_buffer_size")
+		}
+
+		buf := (*C.uint8_t)(unsafe.Pointer(
+			C.av_malloc(bufferSize(video.bufSize))))
+
+		if buf == nil {
+			return fmt.Errorf(
+				"couldn't allocate an AV buffer")
+		}
+
+		status := C.av_image_fill_arrays(&video.dstFrame.data[0],
+			&video.dstFrame.linesize[0], buf,
+			C.enum_AVPixelFormat(video.format),
+			C.int(width), C.int(height), 1)
+
+		if status < 0 {
+			C.av_free(unsafe.Pointer(buf))
+			return newAVError(status, "av_image_fill_arrays")
+		}
+
+		video.frameWidth = width
+		video.frameHeight = height
+	}
+
+	// The borders are not touched by the
+	// scaler, so they are filled only when
+	// the picture placement changes.
+	if geometry != video.geometry &&
+		geometry.dstRect != image.Rect(0, 0, width, height) {
+		err := fillPadding(video.dstFrame, video.format,
+			width, height, video.options.padding())
+
+		if err != nil {
+			return err
+		}
+	}
+
+	video.geometry = geometry
+	video.swsCtx = C.sws_getCachedContext(video.swsCtx,
+		C.int(geometry.srcRect.Dx()), C.int(geometry.srcRect.Dy()),
+		srcFormat, C.int(geometry.dstRect.Dx()),
+		C.int(geometry.dstRect.Dy()),
+		C.enum_AVPixelFormat(video.format),
+		C.int(video.options.algorithm()), nil, nil, nil)
 
 	if video.swsCtx == nil {
 		return fmt.Errorf(
@@ -106,44 +267,353 @@ func (video *VideoStream) OpenDecode(width, height int, alg InterpolationAlgorit
 
 // ReadFrame reads the next frame from the stream.
 func (video *VideoStream) ReadFrame() (Frame, bool, error) {
-	return video.ReadVideoFrame()
+	frame, ok, err := video.ReadVideoFrame()
+
+	// The nil frame pointer shouldn't
+	// become a non-nil interface.
+	if frame == nil {
+		return nil, ok, err
+	}
+
+	return frame, ok, err
 }
 
 // ReadVideoFrame reads the next video frame
 // from the video stream.
+//
+// If the packet is depleted and the decoder
+// needs more data, no frame is returned with
+// 'true'. If the stream is completely drained,
+// 'false' is returned.
 func (video *VideoStream) ReadVideoFrame() (*VideoFrame, bool, error) {
-	ok, err := video.read()
+	got, ok, err := video.picture()
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !got {
+		return nil, ok, nil
+	}
+
+	if video.format == PixelFormatNative {
+		return video.nativeFrame()
+	}
+
+	frame := video.newFrame()
+	data := frame.buffer(int(video.bufSize))
+
+	if video.rotation == 0 && !video.flip {
+		copy(data, unsafe.Slice((*byte)(unsafe.Pointer(
+			video.dstFrame.data[0])), video.bufSize))
+	} else {
+		err = video.rotateOutput(data)
+
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	width, height := video.outputSize()
+	err = frame.fill(video, int64(video.frame.pts),
+		int(video.frame.coded_picture_number),
+		video.displayIndex(), width, height,
+		video.format, data)
 
 	if err != nil {
 		return nil, false, err
 	}
 
+	return frame, true, nil
+}
+
+// ReadVideoFrameInto reads the next video frame
+// from the video stream into the image without
+// allocating memory. The stream should be opened
+// for decoding into the RGBA format, and the
+// image should be of the output frame size.
+//
+// The first returned value tells whether
+// the frame was obtained. The second one
+// has the same meaning as in ReadVideoFrame.
+//
+// If the image size doesn't match the frame,
+// an error wrapping io.ErrShortBuffer is
+// returned. The frame is kept until it's
+// read into the image of the right size.
+func (video *VideoStream) ReadVideoFrameInto(dst *image.RGBA) (bool, bool, error) {
+	if video.format != PixelFormatRGBA {
+		return false, false, fmt.Errorf(
+			"couldn't read the frame into an RGBA image: the output format is %s",
+			video.format)
+	}
+
+	// The frame size is known after the
+	// first frame, so the image is checked
+	// before the next one is decoded.
+	if video.frameWidth > 0 && video.frameHeight > 0 {
+		err := video.checkImage(dst)
+
+		if err != nil {
+			return false, true, err
+		}
+	}
+
+	got, ok, err := video.picture()
+
+	if err != nil || !got {
+		return false, ok, err
+	}
+
+	// The frame size may change
+	// in the middle of the stream.
+	err = video.checkImage(dst)
+
+	if err != nil {
+		video.held = true
+		return false, true, err
+	}
+
+	src := unsafe.Slice((*byte)(unsafe.Pointer(
+		video.dstFrame.data[0])), video.bufSize)
+	transformPlane(dst.Pix, dst.Stride, src,
+		int(video.dstFrame.linesize[0]), video.frameWidth,
+		video.frameHeight, 4, video.rotation, video.flip)
+
+	return true, true, nil
+}
+
+// checkImage returns an error if the image
+// is not of the output frame size.
+func (video *VideoStream) checkImage(dst *image.RGBA) error {
+	width, height := video.outputSize()
+
+	if width != dst.Rect.Dx() || height != dst.Rect.Dy() {
+		return fmt.Errorf(
+			"couldn't read the %dx%d frame into the %dx%d image: %w",
+			width, height, dst.Rect.Dx(), dst.Rect.Dy(),
+			io.ErrShortBuffer)
+	}
+
+	return nil
+}
+
+// picture returns the frame kept after the
+// previous read or decodes the next one.
+// The returned values are the same as
+// the ones of decode.
+func (video *VideoStream) picture() (bool, bool, error) {
+	if video.held {
+		video.held = false
+
+		return true, true, nil
+	}
+
+	return video.decode()
+}
+
+// decode decodes the next frame and converts
+// it into the output format unless the native
+// format is requested.
+//
+// The first returned value tells whether
+// the frame was obtained. The second one
+// is 'false' if the stream is drained.
+func (video *VideoStream) decode() (bool, bool, error) {
+	ok, err := video.read()
+
+	if err != nil {
+		return false, false, err
+	}
+
 	if ok && video.skip {
-		return nil, true, nil
+		return false, true, nil
 	}
 
 	// No more data.
 	if !ok {
-		return nil, false, nil
+		return false, false, nil
+	}
+
+	if video.format == PixelFormatNative {
+		return true, true, nil
 	}
 
-	C.sws_scale(video.swsCtx, &video.frame.data[0],
+	// The frame size can change in the middle
+	// of the stream, so the cached SWS context
+	// is updated for every frame.
+	sar := video.frame.sample_aspect_ratio
+
+	if sar.num <= 0 || sar.den <= 0 {
+		sar = video.codecParams.sample_aspect_ratio
+	}
+
+	err = video.initScaler(video.frame.width, video.frame.height,
+		C.enum_AVPixelFormat(video.frame.format), sar)
+
+	if err != nil {
+		return false, false, err
+	}
+
+	// Only the cropped part of the picture is
+	// scaled into its place in the output frame.
+	src := video.geometry.srcRect
+	dst := video.geometry.dstRect
+	srcData := planeOffsets(&video.frame.data, &video.frame.linesize,
+		PixelFormat(video.frame.format), src.Min.X, src.Min.Y)
+	dstData := planeOffsets(&video.dstFrame.data, &video.dstFrame.linesize,
+		video.format, dst.Min.X, dst.Min.Y)
+
+	C.sws_scale(video.swsCtx, &srcData[0],
 		&video.frame.linesize[0], 0,
-		video.codecCtx.height,
-		&video.rgbaFrame.data[0],
-		&video.rgbaFrame.linesize[0])
-
-	data := C.GoBytes(unsafe.
-		Pointer(video.rgbaFrame.data[0]),
-		video.bufSize)
-	frame := newVideoFrame(video, int64(video.frame.pts),
+		C.int(src.Dy()), &dstData[0],
+		&video.dstFrame.linesize[0])
+
+	return true, true, nil
+}
+
+// nativeFrame copies the decoded frame
+// without converting its pixel format.
+func (video *VideoStream) nativeFrame() (*VideoFrame, bool, error) {
+	format := C.enum_AVPixelFormat(video.frame.format)
+	size := C.av_image_get_buffer_size(format,
+		video.frame.width, video.frame.height, 1)
+
+	if size < 0 {
+		return nil, false, newAVError(size, "av_image_get_buffer_size")
+	}
+
+	frame := video.newFrame()
+	data := frame.buffer(int(size))
+
+	if size > 0 {
+		status := C.av_image_copy_to_buffer(
+			(*C.uint8_t)(unsafe.Pointer(&data[0])), size,
+			&video.frame.data[0], &video.frame.linesize[0],
+			format, video.frame.width, video.frame.height, 1)
+
+		if status < 0 {
+			return nil, false, newAVError(status, "av_image_copy_to_buffer")
+		}
+	}
+
+	err := frame.fill(video, int64(video.frame.pts),
 		int(video.frame.coded_picture_number),
-		int(video.frame.display_picture_number),
-		int(video.codecCtx.width), int(video.codecCtx.height), data)
+		video.displayIndex(), int(video.frame.width),
+		int(video.frame.height), PixelFormat(format), data)
+
+	if err != nil {
+		return nil, false, err
+	}
 
 	return frame, true, nil
 }
 
+// newFrame returns a video frame from the
+// frame pool of the stream if it's used.
+func (video *VideoStream) newFrame() *VideoFrame {
+	if video.pool != nil {
+		if frame, ok := video.pool.Get().(*VideoFrame); ok {
+			return frame
+		}
+	}
+
+	frame := new(VideoFrame)
+	frame.pool = video.pool
+
+	return frame
+}
+
+// frameRate returns the average frame rate
+// of the stream or the guessed one if the
+// average frame rate is unknown.
+func (video *VideoStream) frameRate() C.AVRational {
+	info := video.properties()
+
+	if info.avgFrameRate.num > 0 &&
+		info.avgFrameRate.den > 0 {
+		return info.avgFrameRate
+	}
+
+	return info.realFrameRate
+}
+
+// startTime returns the timestamp of the
+// first frame of the stream or 0 if
+// it's unknown.
+func (video *VideoStream) startTime() int64 {
+	startTime := video.properties().startTime
+
+	if startTime == NoTimestamp {
+		return 0
+	}
+
+	return startTime
+}
+
+// displayIndex returns the number of the
+// decoded frame in the display order. It's
+// derived from the frame timestamp if the
+// frame rate of the stream is known.
+func (video *VideoStream) displayIndex() int {
+	pts := int64(video.frame.best_effort_timestamp)
+
+	if pts == NoTimestamp {
+		return int(video.frame.display_picture_number)
+	}
+
+	frameRate := video.frameRate()
+
+	if frameRate.num <= 0 || frameRate.den <= 0 {
+		return int(video.frame.display_picture_number)
+	}
+
+	return int(C.av_rescale_q(C.int64_t(pts-video.startTime()),
+		video.properties().timeBase, C.AVRational{
+			num: frameRate.den, den: frameRate.num}))
+}
+
+// attachedPicture decodes the
+// picture attached to the stream.
+func (video *VideoStream) attachedPicture() (image.Image, error) {
+	if video.inner == nil || video.inner.attached_pic.size <= 0 {
+		return nil, fmt.Errorf(
+			"the stream %d has no attached picture",
+			video.Index())
+	}
+
+	dec, err := NewDecoder(video)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer dec.Close()
+
+	frames, err := dec.Decode(newPacket(
+		video.media, &video.inner.attached_pic))
+
+	if err != nil {
+		return nil, err
+	}
+
+	drained, err := dec.Decode(nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	frames = append(frames, drained...)
+
+	if len(frames) <= 0 {
+		return nil, fmt.Errorf(
+			"couldn't decode the attached picture of the stream %d",
+			video.Index())
+	}
+
+	return frames[0].(*VideoFrame).Image(), nil
+}
+
 // Close closes the video stream for decoding.
 func (video *VideoStream) Close() error {
 	err := video.close()
@@ -152,8 +622,14 @@ func (video *VideoStream) Close() error {
 		return err
 	}
 
-	C.av_free(unsafe.Pointer(video.rgbaFrame))
-	video.rgbaFrame = nil
+	C.av_freep(unsafe.Pointer(&video.dstFrame.data[0]))
+	C.av_free(unsafe.Pointer(video.dstFrame))
+	video.dstFrame = nil
+	video.frameWidth = 0
+	video.frameHeight = 0
+	video.geometry = scaleGeometry{}
+	video.rotation = 0
+	video.flip = false
 	C.sws_freeContext(video.swsCtx)
 	video.swsCtx = nil
 
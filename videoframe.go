@@ -14,33 +14,244 @@ import "image"
 // of a video stream.
 type VideoFrame struct {
 	baseFrame
-	img *image.RGBA
+	format  PixelFormat
+	width   int
+	height  int
+	data    []byte
+	planes  [][]byte
+	strides []int
+	heights []int
 }
 
-// Data returns a byte slice of RGBA
-// pixels of the frame image.
+// Data returns a byte slice of all the
+// planes of the frame image stored one
+// after another without padding.
+//
+// For the RGBA format it's the
+// RGBA pixels of the frame.
 func (frame *VideoFrame) Data() []byte {
-	return frame.img.Pix
+	return frame.data
 }
 
-// Image returns the RGBA image of the frame.
-func (frame *VideoFrame) Image() *image.RGBA {
-	return frame.img
+// Format returns the pixel
+// format of the frame.
+func (frame *VideoFrame) Format() PixelFormat {
+	return frame.format
 }
 
-// newVideoFrame returns a newly created video frame.
-func newVideoFrame(stream Stream, pts int64, indCoded, indDisplay, width, height int, pix []byte) *VideoFrame {
-	upLeft := image.Point{0, 0}
-	lowRight := image.Point{width, height}
-	img := image.NewRGBA(image.Rectangle{upLeft, lowRight})
-	frame := new(VideoFrame)
+// Width returns the width
+// of the frame in pixels.
+func (frame *VideoFrame) Width() int {
+	return frame.width
+}
+
+// Height returns the height
+// of the frame in pixels.
+func (frame *VideoFrame) Height() int {
+	return frame.height
+}
+
+// Planes returns the planes
+// of the frame image.
+func (frame *VideoFrame) Planes() [][]byte {
+	return frame.planes
+}
+
+// Strides returns the length of the
+// row of each plane in bytes.
+func (frame *VideoFrame) Strides() []int {
+	return frame.strides
+}
+
+// Image returns the image of the frame:
+// *image.RGBA for RGBA, *image.Gray for
+// GRAY8, *image.YCbCr for planar 8-bit
+// YUV formats and *image.NRGBA converted
+// from the other packed RGB formats.
+//
+// The Go YCbCr model is full range, so the
+// limited range YUV images look washed out.
+// If the format has no matching Go image
+// type, nil is returned.
+func (frame *VideoFrame) Image() image.Image {
+	rect := image.Rect(0, 0, frame.width, frame.height)
+
+	switch frame.format {
+	case PixelFormatRGBA:
+		return &image.RGBA{
+			Pix:    frame.planes[0],
+			Stride: frame.strides[0],
+			Rect:   rect,
+		}
+
+	case PixelFormatGray8:
+		return &image.Gray{
+			Pix:    frame.planes[0],
+			Stride: frame.strides[0],
+			Rect:   rect,
+		}
+
+	case PixelFormatRGB24:
+		return frame.nrgba(3, 0, 1, 2, -1)
+
+	case PixelFormatBGR24:
+		return frame.nrgba(3, 2, 1, 0, -1)
+
+	case PixelFormatBGRA:
+		return frame.nrgba(4, 2, 1, 0, 3)
+	}
+
+	if img, ok := frame.YCbCr(); ok {
+		return img
+	}
+
+	return nil
+}
+
+// YCbCr returns the image of the frame
+// sharing the frame planes if the frame
+// is in the planar 8-bit YUV format
+// (e.g. 4:2:0, 4:2:2 or 4:4:4).
+func (frame *VideoFrame) YCbCr() (*image.YCbCr, bool) {
+	ratio, ok := subsampleRatio(frame.format)
+
+	if !ok {
+		return nil, false
+	}
+
+	return &image.YCbCr{
+		Y:              frame.planes[0],
+		Cb:             frame.planes[1],
+		Cr:             frame.planes[2],
+		YStride:        frame.strides[0],
+		CStride:        frame.strides[1],
+		SubsampleRatio: ratio,
+		Rect:           image.Rect(0, 0, frame.width, frame.height),
+	}, true
+}
+
+// nrgba converts the packed RGB image into
+// NRGBA using the specified pixel size and
+// component offsets. The negative alpha
+// offset means the image is opaque.
+func (frame *VideoFrame) nrgba(size, r, g, b, a int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0,
+		frame.width, frame.height))
+	src := frame.planes[0]
+
+	for y := 0; y < frame.height; y++ {
+		row := src[y*frame.strides[0]:]
+		dst := img.Pix[y*img.Stride:]
+
+		for x := 0; x < frame.width; x++ {
+			pixel := row[x*size : x*size+size]
+			alpha := uint8(0xff)
+
+			if a >= 0 {
+				alpha = pixel[a]
+			}
+
+			dst[x*4] = pixel[r]
+			dst[x*4+1] = pixel[g]
+			dst[x*4+2] = pixel[b]
+			dst[x*4+3] = alpha
+		}
+	}
+
+	return img
+}
+
+// subsampleRatio returns the Go chroma
+// subsample ratio of the planar 8-bit
+// YUV pixel format.
+func subsampleRatio(format PixelFormat) (image.YCbCrSubsampleRatio, bool) {
+	switch C.enum_AVPixelFormat(format) {
+	case C.AV_PIX_FMT_YUV420P, C.AV_PIX_FMT_YUVJ420P:
+		return image.YCbCrSubsampleRatio420, true
+
+	case C.AV_PIX_FMT_YUV422P, C.AV_PIX_FMT_YUVJ422P:
+		return image.YCbCrSubsampleRatio422, true
+
+	case C.AV_PIX_FMT_YUV444P, C.AV_PIX_FMT_YUVJ444P:
+		return image.YCbCrSubsampleRatio444, true
+
+	case C.AV_PIX_FMT_YUV440P, C.AV_PIX_FMT_YUVJ440P:
+		return image.YCbCrSubsampleRatio440, true
+
+	case C.AV_PIX_FMT_YUV411P:
+		return image.YCbCrSubsampleRatio411, true
+
+	case C.AV_PIX_FMT_YUV410P:
+		return image.YCbCrSubsampleRatio410, true
+
+	default:
+		return 0, false
+	}
+}
+
+// Release returns the frame to the frame
+// pool of its stream, so its memory is
+// reused for the next frames. The frame
+// must not be used after that.
+//
+// If the stream doesn't use the
+// frame pool, nothing is done.
+func (frame *VideoFrame) Release() {
+	if frame.pool != nil {
+		frame.pool.Put(frame)
+	}
+}
+
+// buffer returns the data buffer of the frame
+// of the specified size reusing the memory
+// of the previous data if possible.
+func (frame *VideoFrame) buffer(size int) []byte {
+	if cap(frame.data) < size {
+		frame.data = make([]byte, size)
+	}
+
+	return frame.data[:size]
+}
+
+// fill sets the properties of the frame
+// and splits its data into planes.
+func (frame *VideoFrame) fill(stream Stream, pts int64, indCoded, indDisplay, width, height int, format PixelFormat, data []byte) error {
+	// The plane layout is kept
+	// while the frame is reused.
+	if frame.strides == nil || frame.format != format ||
+		frame.width != width || frame.height != height {
+		strides, heights, err := format.planeLayout(width, height)
+
+		if err != nil {
+			return err
+		}
+
+		frame.strides = strides
+		frame.heights = heights
+		frame.planes = make([][]byte, len(strides))
+	}
+
+	offset := 0
+
+	for i := range frame.strides {
+		size := frame.strides[i] * frame.heights[i]
+
+		if offset+size > len(data) {
+			size = len(data) - offset
+		}
+
+		frame.planes[i] = data[offset : offset+size]
+		offset += size
+	}
 
-	img.Pix = pix
 	frame.stream = stream
 	frame.pts = pts
-	frame.img = img
+	frame.format = format
+	frame.width = width
+	frame.height = height
+	frame.data = data
 	frame.indexCoded = indCoded
 	frame.indexDisplay = indDisplay
 
-	return frame
+	return nil
 }